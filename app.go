@@ -1,58 +1,425 @@
 package zebra
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"github.com/raythorn/zebra/cache"
+	"github.com/raythorn/zebra/db"
 	"github.com/raythorn/zebra/log"
 	"github.com/raythorn/zebra/router"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
-	// "os"
-	// "os/exec"
-	// "path/filepath"
-	"time"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 )
 
-type app struct {
+//App is a zebra server instance, it owns its router, environment and engines, so several
+//apps can run in one process, e.g. public api and admin api on different ports. The
+//package-level functions work on a default App.
+type App struct {
 	router.Router
-	g *router.Group
+	Env *Environment
+
+	cache  cache.Cache
+	db     db.Database
+	shared bool
+
+	mutex     sync.Mutex
+	servers   []*http.Server
+	listeners map[string]net.Listener
+	certs     *certStore
+	once      sync.Once
+	done      chan struct{}
+	err       error
+
+	onstart    []func() error
+	onready    []func() error
+	onshutdown []func() error
+	checks     map[string]Checker
+	ready      bool
+
+	conns     map[net.Conn]http.ConnState
+	active    int
+	idle      int
+	connstate func(active, idle int)
+}
+
+//New creates an isolated App with its own router and environment
+func New() *App {
+	return &App{
+		Router: router.New(),
+		Env:    newEnvironment(),
+		done:   make(chan struct{}),
+		conns:  make(map[net.Conn]http.ConnState),
+
+		listeners: make(map[string]net.Listener),
+	}
 }
 
-func (a *app) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+//ServeHTTP implements http.Handler, so App can serve in other muxes without Run, such as
+//mux.Handle("/api/", http.StripPrefix("/api", app))
+func (a *App) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	a.Handle(rw, req)
 }
 
-func (a *app) run() {
+//RegisterCache makes a cache engine with uri for this app, the engine will be destroyed
+//when app shut down
+func (a *App) RegisterCache(uri string, factory cache.Factory) error {
+	engine := factory.Make(uri)
+	if engine == nil {
+		return errors.New("Zebra: register cache engine failed")
+	}
 
-	finish := make(chan bool, 1)
+	a.cache = engine
+	return nil
+}
 
-	go func() {
-		host := Env.Host()
-		port := Env.Port()
-		addr := fmt.Sprintf("%s:%d", host, port)
+//SwapCache replaces cache engine of this app with an engine made already, nil removes it, and
+//returns the engine replaced, which is not destroyed
+func (a *App) SwapCache(engine cache.Cache) cache.Cache {
+	old := a.cache
+	a.cache = engine
+	return old
+}
+
+//Cache returns the cache engine of this app, the default app falls back to the engine
+//registered with cache.Register
+func (a *App) Cache() cache.Cache {
+	if a.cache == nil && a.shared {
+		return cache.Engine()
+	}
+
+	return a.cache
+}
+
+//RegisterDB makes a database engine with uri for this app, the engine will be destroyed
+//when app shut down
+func (a *App) RegisterDB(uri string, factory db.Factory) error {
+	engine := factory.Make(uri)
+	if engine == nil {
+		return errors.New("Zebra: register database engine failed")
+	}
+
+	a.db = engine
+	return nil
+}
+
+//DB returns the database engine of this app, the default app falls back to the engine
+//registered with db.Register
+func (a *App) DB() db.Database {
+	if a.db == nil && a.shared {
+		return db.Engine()
+	}
 
-		log.Info("Server listen at %s", addr)
+	return a.db
+}
 
-		if err := http.ListenAndServe(addr, a); err != nil {
-			log.Error("ListenAndServe fail")
-			time.Sleep(100 * time.Microsecond)
-			finish <- true
+//Run starts http(s) server of this app, and blocks until the app is shut down
+func (a *App) Run() {
+
+	if err := a.Env.Validate(); err != nil {
+		log.Fatal("%s", err)
+	}
+
+	if err := a.Router.Validate(); err != nil {
+		log.Fatal("%s", err)
+	}
+
+	a.mutex.Lock()
+	onstart, onready := a.onstart, a.onready
+	a.mutex.Unlock()
+
+	if err := runHooks("OnStart", onstart); err != nil {
+		log.Fatal("Start fail: %s", err)
+	}
+
+	failed := make(chan error, 2)
+
+	addr := address(a.Env.Host(), a.Env.Port())
+	ln, err := a.listen("http", addr)
+	if err != nil {
+		log.Fatal("Listen %s fail: %s", addr, err)
+	}
+
+	var handler http.Handler = a
+	if a.Env.TLSRedirect() {
+		handler = redirect(a.Env)
+	}
+
+	var h2s *http2.Server
+	if a.Env.H2C() {
+		h2s = &http2.Server{
+			MaxConcurrentStreams: a.Env.H2CMaxStreams(),
+			IdleTimeout:          a.Env.IdleTimeout(),
+		}
+		handler = h2c.NewHandler(handler, h2s)
+	}
+
+	server := a.serve(addr, handler)
+	if h2s != nil {
+		//Let h2c connections receive GOAWAY on shutdown
+		if err := http2.ConfigureServer(server, h2s); err != nil {
+			log.Fatal("Configure h2c fail: %s", err)
+		}
+	}
+
+	go func() {
+		log.Info("Server listen at %s", ln.Addr())
+
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Error("Serve fail: %s", err)
+			failed <- err
 		}
 	}()
 
-	if Env.TLS() {
+	if a.Env.TLS() {
+		store, err := newCertStore(a.Env.TLSCerts(), a.Env.TLSKeys())
+		if err != nil {
+			log.Fatal("%s", err)
+		}
+
+		config, err := tlsConfig(a.Env, store)
+		if err != nil {
+			log.Fatal("%s", err)
+		}
+
+		if interval := a.Env.TLSReload(); interval > 0 {
+			go store.watch(interval)
+		}
+
+		a.mutex.Lock()
+		a.certs = store
+		a.mutex.Unlock()
+
+		addr := address(a.Env.TLSHost(), a.Env.TLSPort())
+		ln, err := a.listen("https", addr)
+		if err != nil {
+			log.Fatal("Listen %s fail: %s", addr, err)
+		}
+
+		server := a.serve(addr, a)
+		server.TLSConfig = config
 		go func() {
-			cert := Env.TLSCert()
-			key := Env.TLSKey()
-			host := Env.TLSHost()
-			port := Env.TLSPort()
-
-			addr := fmt.Sprintf("%s:%d", host, port)
-			if err := http.ListenAndServeTLS(addr, cert, key, a); err != nil {
-				log.Error("ListenAndServeTLS fail")
-				time.Sleep(100 * time.Microsecond)
-				finish <- true
+			log.Info("Server listen at %s with TLS", ln.Addr())
+
+			if err := server.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+				log.Error("ServeTLS fail: %s", err)
+				failed <- err
 			}
 		}()
 	}
 
-	<-finish
+	a.mutex.Lock()
+	a.ready = true
+	a.mutex.Unlock()
+
+	runHooks("OnReady", onready)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	upgrade := make(chan os.Signal, 1)
+	if len(upgradeSignals) > 0 {
+		signal.Notify(upgrade, upgradeSignals...)
+		defer signal.Stop(upgrade)
+	}
+
+	for running := true; running; {
+		select {
+		case sig := <-quit:
+			log.Info("Receive signal %s, shutting down", sig)
+			a.shutdownWithTimeout()
+			running = false
+		case sig := <-upgrade:
+			log.Info("Receive signal %s, upgrading", sig)
+			if err := a.Upgrade(); err != nil {
+				log.Error("Upgrade fail: %s", err)
+				continue
+			}
+			a.shutdownWithTimeout()
+			running = false
+		case <-failed:
+			a.shutdownWithTimeout()
+			running = false
+		case <-a.done:
+			running = false
+		}
+	}
+
+	<-a.done
+}
+
+//listen creates or inherits listener with name, and tracks it for binary upgrade
+func (a *App) listen(name, addr string) (net.Listener, error) {
+	ln, err := listen(name, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	a.listeners[name] = ln
+	a.mutex.Unlock()
+
+	return ln, nil
+}
+
+//serve creates a http server for addr with limits in Env and tracks it, so it can be
+//drained on shutdown
+func (a *App) serve(addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       a.Env.ReadTimeout(),
+		ReadHeaderTimeout: a.Env.ReadHeaderTimeout(),
+		WriteTimeout:      a.Env.WriteTimeout(),
+		IdleTimeout:       a.Env.IdleTimeout(),
+		MaxHeaderBytes:    a.Env.MaxHeaderBytes(),
+		ConnState:         a.track,
+	}
+	server.SetKeepAlivesEnabled(a.Env.KeepAlive())
+
+	a.mutex.Lock()
+	a.servers = append(a.servers, server)
+	a.mutex.Unlock()
+
+	return server
+}
+
+//ConnState sets a hook which will be called with counts of active and idle connections, each
+//time a connection changes state
+func (a *App) ConnState(hook func(active, idle int)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.connstate = hook
+}
+
+//Connections returns counts of active and idle connections
+func (a *App) Connections() (active, idle int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.active, a.idle
+}
+
+//track counts connections by state, new connections are counted as active
+func (a *App) track(conn net.Conn, state http.ConnState) {
+
+	a.mutex.Lock()
+
+	if prev, ok := a.conns[conn]; ok {
+		if prev == http.StateIdle {
+			a.idle--
+		} else {
+			a.active--
+		}
+	}
+
+	switch state {
+	case http.StateNew, http.StateActive:
+		a.active++
+		a.conns[conn] = state
+	case http.StateIdle:
+		a.idle++
+		a.conns[conn] = state
+	default:
+		delete(a.conns, conn)
+	}
+
+	active, idle, hook := a.active, a.idle, a.connstate
+	a.mutex.Unlock()
+
+	if hook != nil {
+		hook(active, idle)
+	}
+}
+
+func (a *App) shutdownWithTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Env.ShutdownTimeout())
+	defer cancel()
+
+	a.Shutdown(ctx)
+}
+
+//Shutdown stops all listeners, waits for in-flight requests until ctx is done, and then
+//releases cache and database engines of this app, the default app also closes log channels
+//and engines registered with cache.Register and db.Register. It only takes effect once.
+func (a *App) Shutdown(ctx context.Context) error {
+
+	a.once.Do(func() {
+		a.Env.StopWatch()
+
+		a.mutex.Lock()
+		a.ready = false
+		servers := a.servers
+		onshutdown := a.onshutdown
+		if a.certs != nil {
+			a.certs.close()
+			a.certs = nil
+		}
+		a.mutex.Unlock()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, len(servers))
+		for _, server := range servers {
+			wg.Add(1)
+			go func(server *http.Server) {
+				defer wg.Done()
+				if err := server.Shutdown(ctx); err != nil {
+					log.Error("Shutdown %s fail: %s", server.Addr, err)
+					server.Close()
+					errs <- err
+				}
+			}(server)
+		}
+		wg.Wait()
+		close(errs)
+
+		a.err = <-errs
+
+		runHooks("OnShutdown", onshutdown)
+
+		if a.cache != nil {
+			if err := a.cache.Factory().Destroy(); err != nil {
+				log.Error("Destroy cache engine fail: %s", err)
+			}
+			a.cache = nil
+		}
+
+		if a.db != nil {
+			if err := a.db.Factory().Destroy(); err != nil {
+				log.Error("Destroy database engine fail: %s", err)
+			}
+			a.db = nil
+		}
+
+		log.Info("Server stopped")
+
+		if a.shared {
+			if cache.Engine() != nil {
+				if err := cache.UnRegister(""); err != nil {
+					log.Error("%s", err)
+				}
+			}
+
+			if db.Engine() != nil {
+				if err := db.UnRegister(); err != nil {
+					log.Error("%s", err)
+				}
+			}
+
+			log.Close()
+		}
+
+		close(a.done)
+	})
+
+	<-a.done
+
+	return a.err
 }
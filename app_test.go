@@ -0,0 +1,141 @@
+package zebra
+
+import (
+	"context"
+	zcontext "github.com/raythorn/zebra/context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+//start runs app on a random port, and returns address of http listener after it's ready
+func start(t *testing.T, app *App) string {
+
+	ready := make(chan struct{})
+	app.Env.Http("127.0.0.1", 0)
+	app.OnReady(func() error {
+		close(ready)
+		return nil
+	})
+
+	go app.Run()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("app is not ready in 5s")
+	}
+
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	return app.listeners["http"].Addr().String()
+}
+
+//slow returns a handler which signals started and blocks until release is closed
+func slow(started chan<- struct{}, release <-chan struct{}) func(*zcontext.Context) {
+	return func(ctx *zcontext.Context) {
+		started <- struct{}{}
+		<-release
+		ctx.WriteString("done")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+
+	app := New()
+	app.Get("/slow", slow(started, release))
+	addr := start(t, app)
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		done <- result{string(body), err}
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Shutdown(context.Background()) }()
+
+	// Shutdown waits for in-flight request, and refuses new connections
+	select {
+	case err := <-stopped:
+		t.Fatalf("Shutdown returns %v before in-flight request finishes", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+		resp.Body.Close()
+		t.Error("new request is served after Shutdown")
+	}
+
+	close(release)
+
+	if r := <-done; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request = %q, %v, want done", r.body, r.err)
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Shutdown = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Shutdown doesn't return after in-flight request finishes")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+
+	app := New()
+	app.Env.EnableGraceful(100 * time.Millisecond)
+	app.Get("/slow", slow(started, release))
+	addr := start(t, app)
+
+	failed := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		failed <- err
+	}()
+	<-started
+
+	begin := time.Now()
+	app.shutdownWithTimeout()
+
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("shutdown with 100ms timeout takes %s", elapsed)
+	}
+
+	if app.err != context.DeadlineExceeded {
+		t.Errorf("error of shutdown = %v, want %v", app.err, context.DeadlineExceeded)
+	}
+
+	// Connection of request not finished in time is closed
+	select {
+	case err := <-failed:
+		if err == nil {
+			t.Error("request not finished before timeout succeeds")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("request not finished before timeout is still running")
+	}
+}
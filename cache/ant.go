@@ -15,10 +15,11 @@ const (
 //application stopped
 type Ant struct {
 	sync.RWMutex
-	cache  map[string]*particle
-	uri    string
-	clock  time.Duration
-	finish bool
+	cache map[string]*particle
+	uri   string
+	clock time.Duration
+	quit  chan bool
+	once  sync.Once
 }
 
 type particle struct {
@@ -37,10 +38,10 @@ func (p *particle) expire() bool {
 
 func (ant *Ant) Make(uri string) Cache {
 	antInstance := &Ant{
-		cache:  make(map[string]*particle),
-		uri:    uri,
-		clock:  AntClockTick,
-		finish: false,
+		cache: make(map[string]*particle),
+		uri:   uri,
+		clock: AntClockTick * time.Second,
+		quit:  make(chan bool),
 	}
 
 	go antInstance.ticker()
@@ -48,8 +49,11 @@ func (ant *Ant) Make(uri string) Cache {
 	return antInstance
 }
 
+//Destroy stops recycling goroutine, it can be called more than once
 func (ant *Ant) Destroy() error {
-	ant.finish = true
+	ant.once.Do(func() {
+		close(ant.quit)
+	})
 
 	return nil
 }
@@ -123,10 +127,9 @@ func (ant *Ant) Get(key string, args ...string) interface{} {
 
 	var values []interface{} = make([]interface{}, 0)
 
-	keys := []interface{}{key, args}
+	keys := append([]string{key}, args...)
 
-	for _, k := range keys {
-		key := k.(string)
+	for _, key := range keys {
 		if val, ok := ant.cache[key]; ok && !val.expire() {
 			values = append(values, val.pit)
 		} else {
@@ -147,9 +150,8 @@ func (ant *Ant) Delete(key string, args ...string) error {
 	ant.Lock()
 	defer ant.Unlock()
 
-	keys := []interface{}{key, args}
-	for _, k := range keys {
-		key := k.(string)
+	keys := append([]string{key}, args...)
+	for _, key := range keys {
 		delete(ant.cache, key)
 	}
 
@@ -321,6 +323,11 @@ func (ant *Ant) Ioctrl(cmd string, args ...interface{}) (interface{}, error) {
 	return nil, errors.New("Ant: Not support Ioctrl")
 }
 
+//Ping always succeeds, Ant lives in memory
+func (ant *Ant) Ping() error {
+	return nil
+}
+
 //Return a factory instance
 func (ant *Ant) Factory() Factory {
 	return ant
@@ -328,26 +335,28 @@ func (ant *Ant) Factory() Factory {
 
 //ticker is a recycling goroutine, will recycle expired data in each clock time
 func (ant *Ant) ticker() {
-	for !ant.finish {
+	clock := time.NewTicker(ant.clock)
+	defer clock.Stop()
 
-		<-time.After(ant.clock)
-		if ant.cache == nil {
+	for {
+		select {
+		case <-ant.quit:
 			return
-		}
-
-		for key := range ant.cache {
-			ant.sentinel(key)
+		case <-clock.C:
+			ant.sentinel()
 		}
 	}
 }
 
-//sentinel check if a specified key is expired, delete key if expired
-func (ant *Ant) sentinel(key string) {
+//sentinel deletes all expired keys
+func (ant *Ant) sentinel() {
 
 	ant.Lock()
 	defer ant.Unlock()
 
-	if particle, ok := ant.cache[key]; ok && particle.expire() {
-		delete(ant.cache, key)
+	for key, particle := range ant.cache {
+		if particle.expire() {
+			delete(ant.cache, key)
+		}
 	}
 }
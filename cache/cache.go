@@ -56,6 +56,12 @@ type Factory interface {
 	Destroy() error
 }
 
+//Pinger is an optional interface of Cache, which checks if cache engine is available
+type Pinger interface {
+	//Ping checks connection of cache engine
+	Ping() error
+}
+
 type cache struct {
 	engine Cache
 }
@@ -84,6 +90,19 @@ func UnRegister(engine string) error {
 	return errors.New("Cache: UnRegister failed")
 }
 
+//Engine returns the registered cache engine, nil if no engine registered
+func Engine() Cache {
+	return cacheInstance.engine
+}
+
+//Swap replaces the registered engine with an engine made already, nil unregisters it, and
+//returns the engine replaced, which is not destroyed
+func Swap(engine Cache) Cache {
+	old := cacheInstance.engine
+	cacheInstance.engine = engine
+	return old
+}
+
 //Set data to cache
 func Set(key string, args ...interface{}) error {
 	if cacheInstance.engine == nil {
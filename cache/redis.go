@@ -47,6 +47,9 @@ func (r *Redis) Make(uri string) Cache {
 
 //Destroy cleanup context if not used
 func (r *Redis) Destroy() error {
+	if r.pool != nil {
+		return r.pool.Close()
+	}
 	return nil
 }
 
@@ -347,3 +350,15 @@ func (r *Redis) Ioctrl(cmd string, args ...interface{}) (result interface{}, err
 
 	return conn.Do(cmd, args...)
 }
+
+//Ping sends PING to redis server
+func (r *Redis) Ping() error {
+	conn := r.pool.Get()
+	if nil == conn {
+		return errors.New("Redis: get connection from pool failed")
+	}
+	defer conn.Close()
+
+	_, err := conn.Do("PING")
+	return err
+}
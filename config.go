@@ -0,0 +1,390 @@
+package zebra
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/raythorn/zebra/log"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//Prefix of OS environment variables which overlay the config files
+const EnvPrefix = "ZEBRA_"
+
+//Load reads configs from files and overlays them with OS environment variables, file format is
+//determined by extension, .json, .yaml/.yml and .toml are supported. Nested keys are flattened
+//with ":", so {"db": {"uri": "..."}} can be retrieved with Get("db:uri"), and arrays are joined
+//with ",", which can be retrieved with GetStrings.
+//
+//For each file, a profile file with the same name, e.g. config.prod.yaml for config.yaml, is
+//loaded after it if exists, see Profile. OS environment variables with prefix ZEBRA_ are loaded
+//at last, "__" in the name stands for ":", and the name matches existing key case-insensitively,
+//so ZEBRA_DB__URI overrides "db:uri", and ZEBRA_PORT overrides "Zebra:PORT". A name matches no
+//key is added in lower case, ZEBRA_RATE_LIMIT sets "rate_limit".
+func (e *Environment) Load(files ...string) error {
+
+	e.Lock()
+	e.files = append(e.files, files...)
+	e.Unlock()
+
+	values, err := e.read()
+	if err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	for key, value := range values {
+		e.data[key] = value
+	}
+	e.loaded = values
+
+	return nil
+}
+
+//Reload re-reads config files and environment variables, changed keys are applied and their
+//subscribers registered with OnChange will be called, keys set in code but not in config source
+//are not affected
+func (e *Environment) Reload() error {
+
+	values, err := e.read()
+	if err != nil {
+		return err
+	}
+
+	type change struct {
+		key      string
+		old, new string
+	}
+
+	var changes []change
+
+	e.Lock()
+	for key, value := range values {
+		if old, ok := e.loaded[key]; !ok || old != value {
+			changes = append(changes, change{key, e.data[key], value})
+			e.data[key] = value
+		}
+	}
+
+	for key := range e.loaded {
+		if _, ok := values[key]; !ok {
+			changes = append(changes, change{key, e.data[key], ""})
+			delete(e.data, key)
+		}
+	}
+	e.loaded = values
+
+	subscribers := make(map[string][]func(string, string), len(e.subscribers))
+	for key, fns := range e.subscribers {
+		subscribers[key] = fns
+	}
+	e.Unlock()
+
+	for _, c := range changes {
+		if c.old == c.new {
+			continue
+		}
+
+		log.Info("Env: %s changed", c.key)
+		for _, fn := range subscribers[c.key] {
+			fn(c.old, c.new)
+		}
+	}
+
+	return nil
+}
+
+//OnChange subscribes changes of key, fn will be called with old and new value when key changed
+//by Reload, value is "" if key is added or removed
+func (e *Environment) OnChange(key string, fn func(old, new string)) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.subscribers == nil {
+		e.subscribers = make(map[string][]func(string, string))
+	}
+
+	e.subscribers[key] = append(e.subscribers[key], fn)
+}
+
+//Watch reloads configs when any config file modified or SIGHUP received, files are checked
+//every interval, and only SIGHUP reloads if interval is not positive. Call StopWatch to stop
+//watching
+func (e *Environment) Watch(interval time.Duration) {
+
+	e.Lock()
+	if e.quit != nil {
+		e.Unlock()
+		return
+	}
+	quit := make(chan bool)
+	e.quit = quit
+	e.Unlock()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		stamps := e.stamps()
+
+		//Nil channel never fires, so files are not checked
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-hup:
+				log.Info("Env: receive SIGHUP, reloading")
+			case <-tick:
+				current := e.stamps()
+				if current == stamps {
+					continue
+				}
+				stamps = current
+				log.Info("Env: config files modified, reloading")
+			case <-quit:
+				return
+			}
+
+			if err := e.Reload(); err != nil {
+				log.Error("Env: reload fail: %s", err)
+			}
+		}
+	}()
+}
+
+//StopWatch stops watching config changes
+func (e *Environment) StopWatch() {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.quit != nil {
+		close(e.quit)
+		e.quit = nil
+	}
+}
+
+//stamps returns a digest of modification time of all config files and their profile files
+func (e *Environment) stamps() string {
+
+	e.RLock()
+	files := make([]string, len(e.files))
+	copy(files, e.files)
+	e.RUnlock()
+
+	profile := e.Profile()
+	stamps := ""
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		for _, f := range []string{file, strings.TrimSuffix(file, ext) + "." + profile + ext} {
+			if fi, err := os.Stat(f); err == nil {
+				stamps += fmt.Sprintf("%s:%d:%d;", f, fi.ModTime().UnixNano(), fi.Size())
+			}
+		}
+	}
+
+	return stamps
+}
+
+//Set profile, which selects the overlay config files, such as dev, staging and prod
+func (e *Environment) SetProfile(profile string) {
+	e.Set("Zebra:PROFILE", profile)
+}
+
+//Get profile, it's set by SetProfile or ZEBRA_PROFILE environment variable, default is "dev"
+func (e *Environment) Profile() string {
+	if profile := e.Get("Zebra:PROFILE"); profile != "" {
+		return profile
+	}
+
+	if profile := os.Getenv(EnvPrefix + "PROFILE"); profile != "" {
+		return profile
+	}
+
+	return "dev"
+}
+
+//Require registers keys which MUST be set before server running, see Validate
+func (e *Environment) Require(keys ...string) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.required = append(e.required, keys...)
+}
+
+//Validate checks all required keys, and the error lists every missing key
+func (e *Environment) Validate() error {
+	e.RLock()
+	defer e.RUnlock()
+
+	var missing []string
+	for _, key := range e.required {
+		if value, ok := e.data[key]; !ok || value == "" {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("Env: missing required keys: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+//read loads all config files with their profile files and environment variables
+func (e *Environment) read() (map[string]string, error) {
+
+	e.RLock()
+	files := make([]string, len(e.files))
+	copy(files, e.files)
+	e.RUnlock()
+
+	profile := e.Profile()
+	values := make(map[string]string)
+
+	for _, file := range files {
+		if err := readFile(file, values); err != nil {
+			return nil, err
+		}
+
+		ext := filepath.Ext(file)
+		overlay := strings.TrimSuffix(file, ext) + "." + profile + ext
+		if _, err := os.Stat(overlay); err == nil {
+			if err := readFile(overlay, values); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	e.RLock()
+	keys := make([]string, 0, len(e.data)+len(values))
+	for key := range e.data {
+		keys = append(keys, key)
+	}
+	e.RUnlock()
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, env := range os.Environ() {
+		pair := strings.SplitN(env, "=", 2)
+		if len(pair) != 2 || !strings.HasPrefix(pair[0], EnvPrefix) || pair[0] == EnvPrefix+"PROFILE" {
+			continue
+		}
+
+		values[envKey(strings.TrimPrefix(pair[0], EnvPrefix), keys)] = pair[1]
+	}
+
+	return values, nil
+}
+
+//settings are names of zebra's own keys, which are saved with prefix "Zebra:"
+var settings = map[string]bool{
+	"HOST": true, "PORT": true, "PROFILE": true, "SHUTDOWNTIMEOUT": true, "HEALTHTIMEOUT": true,
+	"READTIMEOUT": true, "READHEADERTIMEOUT": true, "WRITETIMEOUT": true, "IDLETIMEOUT": true,
+	"MAXHEADERBYTES": true, "KEEPALIVE": true, "H2C": true, "H2CMAXSTREAMS": true,
+	"TLS": true, "TLSCERT": true, "TLSKEY": true, "TLSHOST": true, "TLSPORT": true, "TLSCERTS": true,
+	"TLSKEYS": true, "TLSCIPHERS": true, "TLSMINVERSION": true, "TLSREDIRECT": true, "TLSRELOAD": true,
+}
+
+//envKey maps an environment variable name(without prefix) to config key, zebra's own settings
+//are under "Zebra:", and other names are added in lower case if no existing key matches
+func envKey(name string, keys []string) string {
+	key := strings.Replace(name, "__", ":", -1)
+
+	if settings[strings.ToUpper(key)] {
+		return "Zebra:" + strings.ToUpper(key)
+	}
+
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+
+	return strings.ToLower(key)
+}
+
+func readFile(file string, values map[string]string) error {
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var data interface{}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		err = decoder.Decode(&data)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &data)
+	case ".toml":
+		var table map[string]interface{}
+		err = toml.Unmarshal(content, &table)
+		data = table
+	default:
+		return errors.New("Env: config format not supported: " + file)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Env: parse %s fail: %s", file, err)
+	}
+
+	flatten("", data, values)
+	return nil
+}
+
+//flatten nested config into values with keys joined by ":"
+func flatten(prefix string, data interface{}, values map[string]string) {
+
+	join := func(key interface{}) string {
+		if prefix == "" {
+			return fmt.Sprint(key)
+		}
+		return prefix + ":" + fmt.Sprint(key)
+	}
+
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			flatten(join(key), value, values)
+		}
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			flatten(join(key), value, values)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,140 @@
+package zebra
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "zebra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(file, []byte(`{"Zebra": {"PORT": 8081}, "db": {"uri": "mongodb://dev"}, "hosts": ["a", "b"], "timeout": "5s"}`), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "config.prod.json"), []byte(`{"db": {"uri": "mongodb://prod"}}`), 0600)
+
+	os.Setenv("ZEBRA_PORT", "9090")
+	defer os.Unsetenv("ZEBRA_PORT")
+	os.Setenv("ZEBRA_RATE_LIMIT", "100")
+	defer os.Unsetenv("ZEBRA_RATE_LIMIT")
+
+	env := newEnvironment()
+	env.SetProfile("prod")
+	if err := env.Load(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if port := env.Port(); port != 9090 {
+		t.Errorf("Port() = %d, want 9090", port)
+	}
+
+	if limit := env.GetInt("rate_limit", 0); limit != 100 {
+		t.Errorf("rate_limit = %d, want 100", limit)
+	}
+
+	if uri := env.Get("db:uri"); uri != "mongodb://prod" {
+		t.Errorf("db:uri = %s, want mongodb://prod", uri)
+	}
+
+	if hosts := env.GetStrings("hosts", nil); len(hosts) != 2 || hosts[1] != "b" {
+		t.Errorf("hosts = %v, want [a b]", hosts)
+	}
+
+	if timeout := env.GetDuration("timeout", 0); timeout != 5*time.Second {
+		t.Errorf("timeout = %s, want 5s", timeout)
+	}
+
+	env.Require("db:uri", "secret", "token")
+	if err := env.Validate(); err == nil || err.Error() != "Env: missing required keys: secret, token" {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "zebra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	ioutil.WriteFile(file, []byte("log:\n  level: info\nflag: on\n"), 0600)
+
+	env := newEnvironment()
+	if err := env.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	env.Set("custom", "value")
+
+	var old, new string
+	env.OnChange("log:level", func(o, n string) {
+		old, new = o, n
+	})
+
+	ioutil.WriteFile(file, []byte("log:\n  level: debug\n"), 0600)
+	if err := env.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if old != "info" || new != "debug" {
+		t.Errorf("OnChange got (%s, %s), want (info, debug)", old, new)
+	}
+
+	if flag := env.Get("flag"); flag != "" {
+		t.Errorf("flag = %s, want removed", flag)
+	}
+
+	if custom := env.Get("custom"); custom != "value" {
+		t.Errorf("custom = %s, want value", custom)
+	}
+}
+
+func TestWatch(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "zebra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	ioutil.WriteFile(file, []byte("flag: a\n"), 0600)
+
+	env := newEnvironment()
+	if err := env.Load(file); err != nil {
+		t.Fatal(err)
+	}
+
+	//Non-positive interval only watches SIGHUP
+	env.Watch(0)
+	env.StopWatch()
+
+	changed := make(chan string, 1)
+	env.OnChange("flag", func(old, new string) {
+		changed <- new
+	})
+
+	env.Watch(10 * time.Millisecond)
+	defer env.StopWatch()
+
+	time.Sleep(20 * time.Millisecond)
+	ioutil.WriteFile(file, []byte("flag: b\n"), 0600)
+	os.Chtimes(file, time.Now().Add(time.Second), time.Now().Add(time.Second))
+
+	select {
+	case flag := <-changed:
+		if flag != "b" {
+			t.Errorf("flag = %s, want b", flag)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("config change not watched")
+	}
+}
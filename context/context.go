@@ -12,10 +12,13 @@ package context
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"regexp"
@@ -27,14 +30,23 @@ var (
 	acceptsHTMLRegex = regexp.MustCompile(`(text/html|application/xhtml\+xml)(?:,|$)`)
 	acceptsXMLRegex  = regexp.MustCompile(`(application/xml|text/xml)(?:,|$)`)
 	acceptsJSONRegex = regexp.MustCompile(`(application/json)(?:,|$)`)
+	uuidRegex        = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 )
 
+// ErrBodyTooLarge is returned by reading request body over the limit set by LimitBody
+var ErrBodyTooLarge = errors.New("Context: request body too large")
+
+// URLBuilder builds path of named route with names and values of params
+type URLBuilder func(name string, pairs ...string) (string, error)
+
 type Context struct {
 	rw      http.ResponseWriter
 	request *http.Request
 	data    map[string]string
 	form    map[string]string
 	body    []byte
+	read    bool
+	urlfor  URLBuilder
 }
 
 // Return a new Context instance
@@ -52,10 +64,15 @@ func (c *Context) Request() *http.Request {
 
 // Initialise Context with HTTP Request and ResponseWriter, it will parse the Request header,
 // and it also parse the get/post/put form parameters. NOTE: The Path Regexp param MUST NOT have
-// same name with HTTP Request form param, otherwise, it will override the HTTP form param
-func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
+// same name with HTTP Request form param, otherwise, it will override the HTTP form param.
+// Request body is not read until Body or BodyReader is called, except url encoded form which
+// is parsed here, error of parsing form returns, ErrBodyTooLarge if form body is over the
+// limit of LimitReader.
+func (c *Context) Reset(w http.ResponseWriter, r *http.Request) error {
 	c.request = r
 	c.rw = w
+	c.body = []byte{}
+	c.read = false
 
 	// Parse Request Header
 	for k, v := range c.request.Header {
@@ -63,18 +80,20 @@ func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse Request Form
-	c.request.ParseForm()
+	err := c.request.ParseForm()
 	for k, v := range c.request.Form {
 		c.Set(k, strings.Join(v, ""))
 		c.form[k] = strings.Join(v, "")
 	}
 
-	if c.request.Body != nil {
-		defer c.request.Body.Close()
-		if body, err := ioutil.ReadAll(c.request.Body); err == nil {
-			c.body = body
-		}
-	}
+	return err
+}
+
+// Replace replaces response writer and request without parsing request again, it's used
+// when a wrapped middleware passes new writer or request to next handler
+func (c *Context) Replace(w http.ResponseWriter, r *http.Request) {
+	c.rw = w
+	c.request = r
 }
 
 // Get data from context
@@ -95,10 +114,133 @@ func (c *Context) Set(key, value string) {
 	c.data[key] = value
 }
 
+// ParamInt returns param with key as int, error returns if param is missing or not
+// an integer
+func (c *Context) ParamInt(key string) (int, error) {
+	value, ok := c.data[key]
+	if !ok {
+		return 0, errors.New("Context: param " + key + " not found")
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("Context: param " + key + " is not an integer")
+	}
+
+	return i, nil
+}
+
+// ParamUUID returns param with key as lowercase uuid, error returns if param is
+// missing or not a uuid
+func (c *Context) ParamUUID(key string) (string, error) {
+	value, ok := c.data[key]
+	if !ok {
+		return "", errors.New("Context: param " + key + " not found")
+	}
+
+	if !uuidRegex.MatchString(value) {
+		return "", errors.New("Context: param " + key + " is not a uuid")
+	}
+
+	return strings.ToLower(value), nil
+}
+
+// SetURLBuilder sets builder for URLFor, it's set by router
+func (c *Context) SetURLBuilder(builder URLBuilder) {
+	c.urlfor = builder
+}
+
+// URLFor builds path of named route, such as URLFor("user.show", "id", "42")
+func (c *Context) URLFor(name string, pairs ...string) (string, error) {
+	if c.urlfor == nil {
+		return "", errors.New("Context: URL builder not set")
+	}
+
+	return c.urlfor(name, pairs...)
+}
+
+// AbsURLFor builds absolute URL of named route with site of current request
+func (c *Context) AbsURLFor(name string, pairs ...string) (string, error) {
+	path, err := c.URLFor(name, pairs...)
+	if err != nil {
+		return "", err
+	}
+
+	return c.Site() + path, nil
+}
+
+// Body returns request body, which is read into memory at the first call. Request is
+// aborted with 413 if body is larger than the limit set by LimitBody.
 func (c *Context) Body() []byte {
+	if c.read {
+		return c.body
+	}
+
+	c.read = true
+	if c.request == nil || c.request.Body == nil {
+		return c.body
+	}
+
+	defer c.request.Body.Close()
+	body, err := ioutil.ReadAll(c.request.Body)
+	if err == ErrBodyTooLarge {
+		c.Intercept([]byte("413 request entity too large\n"), http.StatusRequestEntityTooLarge, err.Error())
+	}
+
+	if err == nil {
+		c.body = body
+	}
+
 	return c.body
 }
 
+// BodyReader returns request body for streaming, such as large upload or proxy, reading
+// more than the limit set by LimitBody fails with ErrBodyTooLarge. Body already read by
+// Body is returned from memory.
+func (c *Context) BodyReader() io.ReadCloser {
+	if c.read || c.request.Body == nil {
+		return ioutil.NopCloser(bytes.NewReader(c.Body()))
+	}
+
+	return c.request.Body
+}
+
+// LimitBody limits request body to n bytes, and replaces the limit set before, negative n
+// removes the limit. false returns if body is known to be larger by Content-Length or bytes
+// already read
+func (c *Context) LimitBody(n int64) bool {
+	if n < 0 {
+		n = math.MaxInt64
+	}
+
+	if c.request.ContentLength > n {
+		return false
+	}
+
+	if c.read {
+		return int64(len(c.body)) <= n
+	}
+
+	if c.request.Body == nil {
+		return true
+	}
+
+	// Body over the limit before is broken, as the byte over it is dropped
+	if l, ok := c.request.Body.(*limitedBody); ok {
+		if l.count > l.limit {
+			return false
+		}
+		l.limit = n
+		return l.count <= n
+	}
+
+	if n < math.MaxInt64 {
+		c.request.Body = LimitReader(c.request.Body, n)
+	}
+
+	return true
+}
+
 func (c *Context) Form() map[string]string {
 	return c.form
 }
@@ -292,12 +434,22 @@ func (c *Context) Write(bytes []byte) (int, error) {
 	return c.rw.Write(bytes)
 }
 
+// Aborted is the value ctx.Intercept panics with, router recovers it and finishes
+// current session without treating it as an error
+type Aborted struct {
+	Reason string
+}
+
+func (a *Aborted) Error() string {
+	return "Context: aborted, " + a.Reason
+}
+
 // Intercept write data with http status code, and current session will be finished
 func (c *Context) Intercept(data []byte, code int, reason string) error {
 	c.WriteHeader(code)
 	c.Write(data)
 	c.Flush()
-	panic(reason)
+	panic(&Aborted{Reason: reason})
 }
 
 // JSON write json-like data to client
@@ -349,3 +501,35 @@ func (c *Context) XML(data interface{}, indent bool) error {
 func (c *Context) NotFound() {
 	http.NotFound(c.rw, c.request)
 }
+
+// LimitReader returns body which fails with ErrBodyTooLarge once more than n bytes are read,
+// it limits request body before Reset, and LimitBody of context changes the limit
+func LimitReader(body io.ReadCloser, n int64) io.ReadCloser {
+	return &limitedBody{ReadCloser: body, limit: n}
+}
+
+// limitedBody fails with ErrBodyTooLarge once more than limit bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	count int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.count > l.limit {
+		return 0, ErrBodyTooLarge
+	}
+
+	// Read one more byte to find out body exceeds limit
+	if left := l.limit - l.count; int64(len(p)) > left {
+		p = p[:left+1]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+	l.count += int64(n)
+	if l.count > l.limit {
+		return n - int(l.count-l.limit), ErrBodyTooLarge
+	}
+
+	return n, err
+}
@@ -62,6 +62,12 @@ type Factory interface {
 	Destroy() error
 }
 
+//Pinger is an optional interface of Database, which checks if database engine is available
+type Pinger interface {
+	//Ping checks connection of database engine
+	Ping() error
+}
+
 type database struct {
 	engine Database
 }
@@ -77,8 +83,9 @@ func Register(uri string, factory Factory) error {
 }
 
 func UnRegister() error {
-	if dbInstance != nil {
+	if dbInstance.engine != nil {
 		if err := dbInstance.engine.Factory().Destroy(); err == nil {
+			dbInstance.engine = nil
 			return nil
 		}
 	}
@@ -86,6 +93,11 @@ func UnRegister() error {
 	return errors.New("DB: unregister failed")
 }
 
+//Engine returns the registered database engine, nil if no engine registered
+func Engine() Database {
+	return dbInstance.engine
+}
+
 func Use(db string) error {
 	if dbInstance.engine == nil {
 		return errors.New("DB: engine invalid")
@@ -44,7 +44,10 @@ func (m *MongoDB) Make(uri string) Database {
 
 func (m *MongoDB) Destroy() error {
 
-	m.sess.Close()
+	if m.sess != nil {
+		m.sess.Close()
+		m.sess = nil
+	}
 	return nil
 }
 
@@ -185,6 +188,17 @@ func (m *MongoDB) Ioctrl(cmd string, args ...interface{}) (interface{}, error) {
 	return nil, errors.New("MongDB: invalid args")
 }
 
+//Ping checks connection of mongodb server
+func (m *MongoDB) Ping() error {
+	session := m.session()
+	if session == nil {
+		return errors.New("Cannot connect to mongodb")
+	}
+	defer session.Close()
+
+	return session.Ping()
+}
+
 //Factory return a Factory interface instance, which can create and destroy a database engine
 func (m *MongoDB) Factory() Factory {
 	return m
@@ -0,0 +1,50 @@
+package zebra
+
+import (
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/log"
+	"github.com/raythorn/zebra/openapi"
+	"net/http"
+	"strings"
+)
+
+//OpenAPI serves OpenAPI document of routes at path+"/openapi.json" and path+"/openapi.yaml", and
+//a viewer of document at path, such as OpenAPI("/docs", info). Document is generated for each
+//request, so routes added later are included.
+func (a *App) OpenAPI(path string, info openapi.Info) {
+
+	path = strings.TrimSuffix(path, "/")
+
+	a.Get(path+"/openapi.json", func(ctx *context.Context) {
+		content, err := openapi.Generate(info, a.Routes()).JSON()
+		a.document(ctx, "application/json; charset=utf-8", content, err)
+	})
+
+	a.Get(path+"/openapi.yaml", func(ctx *context.Context) {
+		content, err := openapi.Generate(info, a.Routes()).YAML()
+		a.document(ctx, "application/yaml; charset=utf-8", content, err)
+	})
+
+	viewer := path
+	if viewer == "" {
+		viewer = "/"
+	}
+
+	a.Get(viewer, func(ctx *context.Context) {
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		if err := openapi.Viewer(ctx, info.Title, path+"/openapi.json"); err != nil {
+			log.Error("OpenAPI: render viewer fail: %s", err)
+		}
+	})
+}
+
+func (a *App) document(ctx *context.Context, contentType string, content []byte, err error) {
+	if err != nil {
+		log.Error("OpenAPI: generate document fail: %s", err)
+		http.Error(ctx.ResponseWriter(), "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Write(content)
+}
@@ -2,15 +2,27 @@ package zebra
 
 import (
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 //Simple implement for zebra evironment, you can use this to save your configs.
 //It's thread-safe, so you can have all your fun in erverywhere.
 type Environment struct {
 	sync.RWMutex
-	data map[string]string
+	data        map[string]string
+	files       []string
+	loaded      map[string]string
+	required    []string
+	subscribers map[string][]func(string, string)
+	quit        chan bool
+}
+
+func newEnvironment() *Environment {
+	return &Environment{data: make(map[string]string)}
 }
 
 // Set env variable with a pair of key-value, you cann't use key with prefix "Zebra:", which
@@ -33,6 +45,59 @@ func (e *Environment) Get(key string) string {
 	return ""
 }
 
+//GetInt returns env variable as int, def will return if not exist or not a valid int
+func (e *Environment) GetInt(key string, def int) int {
+	if value := e.Get(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+
+	return def
+}
+
+//GetBool returns env variable as bool, def will return if not exist or not a valid bool
+func (e *Environment) GetBool(key string, def bool) bool {
+	if value := e.Get(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	return def
+}
+
+//GetDuration returns env variable as time.Duration, such as "1m30s", and integer is
+//treated as seconds, def will return if not exist or not a valid duration
+func (e *Environment) GetDuration(key string, def time.Duration) time.Duration {
+	if value := e.Get(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+
+		if i, err := strconv.Atoi(value); err == nil {
+			return time.Duration(i) * time.Second
+		}
+	}
+
+	return def
+}
+
+//GetStrings returns env variable splitted by ",", def will return if not exist
+func (e *Environment) GetStrings(key string, def []string) []string {
+	value := e.Get(key)
+	if value == "" {
+		return def
+	}
+
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+
+	return items
+}
+
 //Delete a env variable with key
 func (e *Environment) Del(key string) {
 	e.Lock()
@@ -60,13 +125,7 @@ func (e *Environment) DisableTLS() {
 
 //Check if HTTPS is enabled
 func (e *Environment) TLS() bool {
-
-	tls := e.Get("Zebra:TLS")
-	if tls == "true" {
-		return true
-	}
-
-	return false
+	return e.GetBool("Zebra:TLS", false)
 }
 
 //Get https cert file
@@ -86,14 +145,7 @@ func (e *Environment) TLSHost() string {
 
 //Get https port, if port not set or error happened, default port 443 will be returned
 func (e *Environment) TLSPort() int {
-	port := e.Get("Zebra:TLSPORT")
-	if port != "" {
-		if p, err := strconv.Atoi(port); err == nil {
-			return p
-		}
-	}
-
-	return 443
+	return e.GetInt("Zebra:TLSPORT", 443)
 }
 
 //Set http host and port, if not set, "localhost:8080" will be used
@@ -112,12 +164,91 @@ func (e *Environment) Host() string {
 
 //Get http listen port
 func (e *Environment) Port() int {
-	port := e.Get("Zebra:PORT")
-	if port != "" {
-		if p, err := strconv.Atoi(port); err == nil {
-			return p
-		}
-	}
+	return e.GetInt("Zebra:PORT", 8080)
+}
+
+//Set timeout for draining in-flight requests when server shutting down
+func (e *Environment) EnableGraceful(timeout time.Duration) {
+	e.Set("Zebra:SHUTDOWNTIMEOUT", timeout.String())
+}
+
+//Get shutdown timeout, if not set or error happened, default 10 seconds will be returned
+func (e *Environment) ShutdownTimeout() time.Duration {
+	return e.GetDuration("Zebra:SHUTDOWNTIMEOUT", 10*time.Second)
+}
+
+//Set timeouts of http(s) server, read timeout covers the whole request including body, write
+//timeout covers the response, and idle timeout limits keep-alive connections waiting for next
+//request, 0 means no timeout
+func (e *Environment) SetTimeout(read, write, idle time.Duration) {
+	e.Set("Zebra:READTIMEOUT", read.String())
+	e.Set("Zebra:WRITETIMEOUT", write.String())
+	e.Set("Zebra:IDLETIMEOUT", idle.String())
+}
+
+//Get read timeout, default 0 means no timeout
+func (e *Environment) ReadTimeout() time.Duration {
+	return e.GetDuration("Zebra:READTIMEOUT", 0)
+}
+
+//Get timeout for reading request headers, default 10 seconds
+func (e *Environment) ReadHeaderTimeout() time.Duration {
+	return e.GetDuration("Zebra:READHEADERTIMEOUT", 10*time.Second)
+}
+
+//Get write timeout, default 0 means no timeout
+func (e *Environment) WriteTimeout() time.Duration {
+	return e.GetDuration("Zebra:WRITETIMEOUT", 0)
+}
+
+//Get idle timeout of keep-alive connections, default 120 seconds
+func (e *Environment) IdleTimeout() time.Duration {
+	return e.GetDuration("Zebra:IDLETIMEOUT", 120*time.Second)
+}
+
+//Set max bytes of request headers, including request line
+func (e *Environment) SetMaxHeaderBytes(size int) {
+	e.Set("Zebra:MAXHEADERBYTES", strconv.Itoa(size))
+}
+
+//Get max bytes of request headers, default 1MB
+func (e *Environment) MaxHeaderBytes() int {
+	return e.GetInt("Zebra:MAXHEADERBYTES", http.DefaultMaxHeaderBytes)
+}
+
+//Enable or disable HTTP keep-alive
+func (e *Environment) SetKeepAlive(enable bool) {
+	e.Set("Zebra:KEEPALIVE", strconv.FormatBool(enable))
+}
+
+//Check if HTTP keep-alive is enabled, default true
+func (e *Environment) KeepAlive() bool {
+	return e.GetBool("Zebra:KEEPALIVE", true)
+}
+
+//Enable HTTP/2 without TLS(h2c) on http listener, both prior knowledge and Upgrade are supported,
+//maxStreams limits concurrent streams of each connection, 0 means default 250
+func (e *Environment) EnableH2C(maxStreams uint32) {
+	e.Set("Zebra:H2C", "true")
+	e.Set("Zebra:H2CMAXSTREAMS", strconv.FormatUint(uint64(maxStreams), 10))
+}
+
+//Disable h2c
+func (e *Environment) DisableH2C() {
+	e.Set("Zebra:H2C", "false")
+}
+
+//Check if h2c is enabled
+func (e *Environment) H2C() bool {
+	return e.GetBool("Zebra:H2C", false)
+}
+
+//Get max concurrent streams of h2c connections, 0 means default
+func (e *Environment) H2CMaxStreams() uint32 {
+	return uint32(e.GetInt("Zebra:H2CMAXSTREAMS", 0))
+}
 
-	return 8080
+//Get timeout of each readiness check, default 3 seconds
+func (e *Environment) HealthTimeout() time.Duration {
+	return e.GetDuration("Zebra:HEALTHTIMEOUT", 3*time.Second)
 }
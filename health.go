@@ -0,0 +1,167 @@
+package zebra
+
+import (
+	"errors"
+	"github.com/raythorn/zebra/cache"
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/db"
+	"github.com/raythorn/zebra/log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//Checker is a readiness check, it returns error if the checked dependency is not available
+type Checker func() error
+
+//checkResult is the json detail of a readiness check
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+//OnStart adds a hook which will be called before listening, app quits if hook fails
+func (a *App) OnStart(hook func() error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.onstart = append(a.onstart, hook)
+}
+
+//OnReady adds a hook which will be called after all listeners are serving
+func (a *App) OnReady(hook func() error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.onready = append(a.onready, hook)
+}
+
+//OnShutdown adds a hook which will be called after in-flight requests drained, and before
+//engines destroyed
+func (a *App) OnShutdown(hook func() error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.onshutdown = append(a.onshutdown, hook)
+}
+
+//Check adds a readiness check with name, which is reported by readiness endpoint, see Health
+func (a *App) Check(name string, check Checker) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.checks == nil {
+		a.checks = make(map[string]Checker)
+	}
+	a.checks[name] = check
+}
+
+//Health adds liveness and readiness endpoints, such as "/healthz" and "/readyz", empty path
+//will be ignored. Liveness always responds 200 while app running, and readiness responds 200
+//only if app is ready and all checks pass, otherwise 503, with json detail of each check.
+//Database and cache engines are checked by default if they implement Pinger.
+func (a *App) Health(liveness, readiness string) {
+
+	if liveness != "" {
+		a.Get(liveness, func(ctx *context.Context) {
+			ctx.JSON(map[string]string{"status": "ok"}, false)
+		})
+	}
+
+	if readiness != "" {
+		a.Get(readiness, a.readiness)
+	}
+}
+
+func (a *App) readiness(ctx *context.Context) {
+
+	a.mutex.Lock()
+	checks := make(map[string]Checker, len(a.checks)+2)
+	for name, check := range a.checks {
+		checks[name] = check
+	}
+	ready := a.ready
+	a.mutex.Unlock()
+
+	if _, ok := checks["db"]; !ok {
+		if pinger, ok := a.DB().(db.Pinger); ok {
+			checks["db"] = pinger.Ping
+		}
+	}
+
+	if _, ok := checks["cache"]; !ok {
+		if pinger, ok := a.Cache().(cache.Pinger); ok {
+			checks["cache"] = pinger.Ping
+		}
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]checkResult, len(checks))
+	timeout := a.Env.HealthTimeout()
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Checker) {
+			defer wg.Done()
+
+			result := checkResult{Status: "ok"}
+			if err := runCheck(check, timeout); err != nil {
+				result = checkResult{Status: "fail", Error: err.Error()}
+			}
+
+			mutex.Lock()
+			results[name] = result
+			mutex.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	status := "ok"
+	code := http.StatusOK
+	if !ready {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	for _, result := range results {
+		if result.Status != "ok" {
+			status = "fail"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	ctx.WriteHeader(code)
+	ctx.JSON(map[string]interface{}{"status": status, "checks": results}, false)
+}
+
+//runCheck runs check and fails if it doesn't finish within timeout
+func runCheck(check Checker, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.New("timeout")
+	}
+}
+
+//runHooks calls all hooks in order, and returns the first error
+func runHooks(stage string, hooks []func() error) error {
+	var first error
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			log.Error("%s hook fail: %s", stage, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
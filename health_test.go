@@ -0,0 +1,118 @@
+package zebra_test
+
+import (
+	"context"
+	"errors"
+	"github.com/raythorn/zebra"
+	"github.com/raythorn/zebra/db"
+	"github.com/raythorn/zebra/zebratest"
+	"testing"
+	"time"
+)
+
+//pingDB is a database engine whose Ping returns err
+type pingDB struct {
+	db.Database
+	err error
+}
+
+func (p *pingDB) Ping() error         { return p.err }
+func (p *pingDB) Factory() db.Factory { return pingFactory{p} }
+
+type pingFactory struct{ db *pingDB }
+
+func (f pingFactory) Make(uri string) db.Database { return f.db }
+func (f pingFactory) Destroy() error              { return nil }
+
+//run starts app on a random port, and returns after it's ready
+func run(t *testing.T, app *zebra.App) {
+
+	ready := make(chan struct{})
+	app.Env.Http("127.0.0.1", 0)
+	app.OnReady(func() error {
+		close(ready)
+		return nil
+	})
+
+	go app.Run()
+	t.Cleanup(func() { app.Shutdown(context.Background()) })
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("app is not ready in 5s")
+	}
+}
+
+func TestHealth(t *testing.T) {
+
+	app := zebra.New()
+	app.Health("/healthz", "/readyz")
+	s := zebratest.New(t, app)
+
+	// App is not ready until Run starts serving
+	s.Get("/healthz").Do().Status(200).JSONPath("status", "ok")
+	s.Get("/readyz").Do().Status(503).JSONPath("status", "unavailable").JSONPath("checks.cache.status", "ok")
+
+	run(t, app)
+
+	s.Get("/readyz").Do().Status(200).JSONPath("status", "ok").JSONPath("checks.cache.status", "ok")
+
+	app.Check("queue", func() error { return errors.New("queue is down") })
+	s.Get("/readyz").Do().
+		Status(503).
+		JSONPath("status", "fail").
+		JSONPath("checks.queue.status", "fail").
+		JSONPath("checks.queue.error", "queue is down")
+}
+
+func TestHealthDB(t *testing.T) {
+
+	app := zebra.New()
+	app.Health("", "/readyz")
+	s := zebratest.New(t, app)
+
+	engine := &pingDB{err: errors.New("connection refused")}
+	if err := app.RegisterDB("mongodb://localhost", pingFactory{engine}); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, app)
+
+	s.Get("/readyz").Do().
+		Status(503).
+		JSONPath("status", "fail").
+		JSONPath("checks.db.status", "fail").
+		JSONPath("checks.db.error", "connection refused")
+
+	engine.err = nil
+	s.Get("/readyz").Do().Status(200).JSONPath("checks.db.status", "ok")
+}
+
+func TestHealthTimeout(t *testing.T) {
+
+	app := zebra.New()
+	app.Health("", "/readyz")
+	app.Env.Set("Zebra:HEALTHTIMEOUT", "50ms")
+	s := zebratest.New(t, app)
+
+	release := make(chan struct{})
+	defer close(release)
+	app.Check("slow", func() error {
+		<-release
+		return nil
+	})
+
+	run(t, app)
+
+	start := time.Now()
+	s.Get("/readyz").Do().
+		Status(503).
+		JSONPath("checks.slow.status", "fail").
+		JSONPath("checks.slow.error", "timeout").
+		JSONPath("checks.cache.status", "ok")
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("readiness with a hanging check takes %s, want about 50ms", elapsed)
+	}
+}
@@ -0,0 +1,179 @@
+package zebra
+
+import (
+	"errors"
+	"fmt"
+	"github.com/raythorn/zebra/log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//First file descriptor passed by systemd socket activation or parent process
+const listenFdsStart = 3
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inheritLns  map[string]net.Listener
+)
+
+//address returns listen address for host and port, host with prefix "unix:" is a unix
+//socket address, such as "unix:/run/zebra.sock", and port is ignored
+func address(host string, port int) string {
+	if strings.HasPrefix(host, "unix:") {
+		return host
+	}
+
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+//listen returns the listener named "http" or "https", inherited listener is used if exist,
+//otherwise creates a new one with addr
+func listen(name, addr string) (net.Listener, error) {
+
+	if ln := inherit(name); ln != nil {
+		log.Info("Inherit %s listener %s", name, ln.Addr())
+		return ln, nil
+	}
+
+	if strings.HasPrefix(addr, "unix:") {
+		path := strings.TrimPrefix(addr, "unix:")
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+
+		return net.Listen("unix", path)
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+//inherit claims listener with name from file descriptors passed by systemd(LISTEN_FDS) or by
+//parent process in a binary upgrade. Descriptors are named with LISTEN_FDNAMES, without
+//names the first one is "http" and the second one is "https".
+func inherit(name string) net.Listener {
+
+	inheritOnce.Do(func() {
+		inheritLns = make(map[string]net.Listener)
+
+		if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+			return
+		}
+
+		count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || count <= 0 {
+			return
+		}
+
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+		defaults := []string{"http", "https"}
+
+		for i := 0; i < count; i++ {
+			fd := listenFdsStart + i
+			file := os.NewFile(uintptr(fd), fmt.Sprintf("listen-fd-%d", fd))
+
+			ln, err := net.FileListener(file)
+			file.Close()
+			if err != nil {
+				log.Error("Inherit file descriptor %d fail: %s", fd, err)
+				continue
+			}
+
+			key := ""
+			if i < len(names) && names[i] != "" && names[i] != "unknown" {
+				key = names[i]
+			} else if i < len(defaults) {
+				key = defaults[i]
+			}
+
+			inheritLns[key] = ln
+		}
+
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	})
+
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	ln := inheritLns[name]
+	delete(inheritLns, name)
+	return ln
+}
+
+//Upgrade starts a new process with the same binary and arguments, and hands listening sockets
+//over to it, the new process starts serving on them immediately. Call Shutdown after Upgrade
+//succeed to drain in-flight requests of current process.
+func (a *App) Upgrade() error {
+
+	a.mutex.Lock()
+	names := make([]string, 0, len(a.listeners))
+	files := make([]*os.File, 0, len(a.listeners))
+	for _, name := range []string{"http", "https"} {
+		ln, ok := a.listeners[name]
+		if !ok {
+			continue
+		}
+
+		f, ok := ln.(interface {
+			File() (*os.File, error)
+		})
+		if !ok {
+			a.mutex.Unlock()
+			return errors.New("Upgrade: listener not support file descriptor")
+		}
+
+		file, err := f.File()
+		if err != nil {
+			a.mutex.Unlock()
+			return err
+		}
+		defer file.Close()
+
+		if unix, ok := ln.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false)
+		}
+
+		names = append(names, name)
+		files = append(files, file)
+	}
+	a.mutex.Unlock()
+
+	if len(files) == 0 {
+		return errors.New("Upgrade: no listener to hand over")
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binary, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("LISTEN_FDS=%d", len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+	)
+
+	if err := cmd.Start(); err != nil {
+		a.mutex.Lock()
+		for _, ln := range a.listeners {
+			if unix, ok := ln.(*net.UnixListener); ok {
+				unix.SetUnlinkOnClose(true)
+			}
+		}
+		a.mutex.Unlock()
+		return err
+	}
+
+	log.Info("Upgrade: new process %d started", cmd.Process.Pid)
+	return nil
+}
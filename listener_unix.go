@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package zebra
+
+import (
+	"os"
+	"syscall"
+)
+
+//Signals trigger a binary upgrade, see App.Upgrade
+var upgradeSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package zebra
+
+import (
+	"os"
+)
+
+//Binary upgrade by signal is not supported on windows
+var upgradeSignals = []os.Signal{}
@@ -156,7 +156,8 @@ func Error(format string, args ...interface{}) {
 //Fatal print fatal error message, and app will quit if this function called
 func Fatal(format string, args ...interface{}) {
 	log4f.log(FATAL, format, args...)
-	os.Exit(0)
+	log4f.close()
+	os.Exit(1)
 }
 
 //Panic print panic message, and app will trigger panic message if called
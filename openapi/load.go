@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+//methods are keys of operations in path item
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+//Load parses OpenAPI document in json or yaml, parameters and request bodies referred with
+//"$ref" are not supported, schemas can be referred
+func Load(data []byte) (*Document, error) {
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("OpenAPI: parse yaml fail: %s", err)
+		}
+
+		content, err := json.Marshal(stringKeys(raw))
+		if err != nil {
+			return nil, fmt.Errorf("OpenAPI: convert yaml fail: %s", err)
+		}
+		data = content
+	}
+
+	doc := &Document{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(doc); err != nil {
+		return nil, fmt.Errorf("OpenAPI: parse document fail: %s", err)
+	}
+
+	return doc, nil
+}
+
+//LoadFile parses OpenAPI document in file, see Load
+func LoadFile(file string) (*Document, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(data)
+}
+
+//stringKeys converts maps parsed from yaml to maps with string keys for json
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = stringKeys(v[i])
+		}
+	}
+
+	return value
+}
+
+//UnmarshalJSON parses operations of path item, and adds parameters of path item into its
+//operations unless overridden
+func (p *PathItem) UnmarshalJSON(data []byte) error {
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var common []*Parameter
+	if params, ok := raw["parameters"]; ok {
+		if err := json.Unmarshal(params, &common); err != nil {
+			return err
+		}
+	}
+
+	item := PathItem{}
+	for key, value := range raw {
+		if !methods[key] {
+			continue
+		}
+
+		op := &Operation{}
+		if err := json.Unmarshal(value, op); err != nil {
+			return err
+		}
+
+		for _, param := range common {
+			overridden := false
+			for _, exist := range op.Parameters {
+				if exist.Name == param.Name && exist.In == param.In {
+					overridden = true
+				}
+			}
+
+			if !overridden {
+				op.Parameters = append(op.Parameters, param)
+			}
+		}
+
+		item[key] = op
+	}
+
+	*p = item
+	return nil
+}
+
+//UnmarshalJSON parses schema, boolean schema such as "additionalProperties: false" is supported
+func (s *Schema) UnmarshalJSON(data []byte) error {
+
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{never: true}
+		return nil
+	}
+
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(s))
+}
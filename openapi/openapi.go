@@ -0,0 +1,179 @@
+// Copyright 2016 Derek Ray. All rights reserved.
+// Use of this source code is governed by Apache License 2.0
+// that can be found in the LICENSE file.
+
+// Package openapi generates OpenAPI 3 document from routes of router.
+//
+// Routes are described with Route.Doc, path params are converted from pattern with
+// their constraints, and request and response types are reflected into schemas, named
+// struct types are put into components and referred by name.
+//
+//	zebra.Get("/users/:id<int>", show).Doc(router.RouteDoc{
+//		Summary:   "Show user",
+//		Tags:      []string{"user"},
+//		Responses: map[int]interface{}{200: User{}, 404: nil},
+//	})
+//	doc := openapi.Generate(openapi.Info{Title: "API", Version: "1.0"}, zebra.Routes())
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/raythorn/zebra/router"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Info is the metadata of API
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+//Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi" yaml:"openapi"`
+	Info       Info                 `json:"info" yaml:"info"`
+	Paths      map[string]*PathItem `json:"paths" yaml:"paths"`
+	Components Components           `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+//Components holds schemas referred by operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+//PathItem holds operations of a path with methods
+type PathItem map[string]*Operation
+
+//Operation describes an operation of a path with method
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+}
+
+//Parameter describes a param of operation
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required" yaml:"required"`
+	Schema      *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+//RequestBody describes request body of operation
+type RequestBody struct {
+	Required bool                  `json:"required" yaml:"required"`
+	Content  map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+//Response describes a response of operation
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+//MediaType holds schema of body
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+//Generate generates document from routes, routes without method such as Any and Mount are
+//ignored, and a route of the same path and method in later table overrides the former
+func Generate(info Info, routes []router.RouteInfo) *Document {
+
+	doc := &Document{
+		OpenAPI:    "3.0.3",
+		Info:       info,
+		Paths:      make(map[string]*PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+
+	for _, route := range routes {
+		if route.Method == "ANY" {
+			continue
+		}
+
+		item, ok := doc.Paths[route.Path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[route.Path] = item
+		}
+
+		(*item)[strings.ToLower(route.Method)] = doc.operation(route)
+	}
+
+	return doc
+}
+
+func (d *Document) operation(route router.RouteInfo) *Operation {
+
+	op := &Operation{
+		OperationID: route.Name,
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, param := range route.Params {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     param.Name,
+			In:       "path",
+			Required: true,
+			Schema:   paramSchema(param),
+		})
+	}
+
+	doc := route.Doc
+	if doc == nil {
+		op.Responses["200"] = &Response{Description: http.StatusText(200)}
+		return op
+	}
+
+	op.Summary = doc.Summary
+	op.Description = doc.Description
+	op.Tags = doc.Tags
+
+	if doc.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: d.schema(doc.Request)}},
+		}
+	}
+
+	codes := make([]int, 0, len(doc.Responses))
+	for code := range doc.Responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		response := &Response{Description: http.StatusText(code)}
+		if body := doc.Responses[code]; body != nil {
+			response.Content = map[string]*MediaType{"application/json": {Schema: d.schema(body)}}
+		}
+		op.Responses[strconv.Itoa(code)] = response
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: http.StatusText(200)}
+	}
+
+	return op
+}
+
+//JSON returns document in indented json
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+//YAML returns document in yaml
+func (d *Document) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
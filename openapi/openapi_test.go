@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/router"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID int64 `json:"id"`
+}
+
+type User struct {
+	Base
+	Name    string            `json:"name"`
+	Email   string            `json:"email,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Friends []*User           `json:"friends,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Created time.Time         `json:"created"`
+	secret  string
+}
+
+func TestGenerate(t *testing.T) {
+
+	handler := func(ctx *context.Context) {}
+
+	r := router.New()
+	r.Get("/users/:id<int>", handler).Name("user.show").Doc(router.RouteDoc{
+		Summary:   "Show user",
+		Tags:      []string{"user"},
+		Responses: map[int]interface{}{200: User{}, 404: nil},
+	})
+	r.Put("/users/:id<int>", handler).Doc(router.RouteDoc{Summary: "Update user", Request: &User{}})
+	r.Get("/files/:kind<enum(a|b)>/*path", handler)
+	r.Any("/any", handler)
+
+	doc := Generate(Info{Title: "API", Version: "1.0"}, r.Routes())
+
+	content, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(path string) interface{} {
+		var node interface{} = data
+		for _, key := range strings.Split(path, "|") {
+			m, ok := node.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			node = m[key]
+		}
+		return node
+	}
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"openapi", "3.0.3"},
+		{"paths|/users/{id}|get|summary", "Show user"},
+		{"paths|/users/{id}|get|operationId", "user.show"},
+		{"paths|/users/{id}|get|responses|200|content|application/json|schema|$ref", "#/components/schemas/User"},
+		{"paths|/users/{id}|get|responses|404|description", "Not Found"},
+		{"paths|/users/{id}|put|requestBody|content|application/json|schema|$ref", "#/components/schemas/User"},
+		{"components|schemas|User|properties|id|format", "int64"},
+		{"components|schemas|User|properties|created|format", "date-time"},
+		{"components|schemas|User|properties|friends|items|$ref", "#/components/schemas/User"},
+		{"components|schemas|User|properties|meta|additionalProperties|type", "string"},
+		{"components|schemas|User|required", "[id name created]"},
+		{"paths|/files/{kind}/{path}|get|responses|200|description", "OK"},
+	}
+
+	for _, c := range cases {
+		if got := lookup(c.path); got == nil || fmt.Sprint(got) != c.want {
+			t.Errorf("%s = %v, want %s", c.path, got, c.want)
+		}
+	}
+
+	params := lookup("paths|/users/{id}|get|parameters").([]interface{})
+	if schema := params[0].(map[string]interface{})["schema"].(map[string]interface{}); schema["type"] != "integer" {
+		t.Errorf("param id schema %v, want integer", schema)
+	}
+
+	if lookup("paths|/any") != nil {
+		t.Errorf("route of any method in document")
+	}
+
+	if _, err := doc.YAML(); err != nil {
+		t.Errorf("YAML() fail: %s", err)
+	}
+}
@@ -0,0 +1,30 @@
+package openapi
+
+import (
+	"html/template"
+	"io"
+)
+
+//RedocScript is url of Redoc bundle loaded by Viewer, pinned to a release, set it to a
+//bundle served by the application to avoid loading script from CDN
+var RedocScript = "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"
+
+var redoc = template.Must(template.New("redoc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>{{.Title}}</title>
+<meta charset="utf-8"/>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>body { margin: 0; padding: 0; }</style>
+</head>
+<body>
+<redoc spec-url="{{.URL}}"></redoc>
+<script src="{{.Script}}"></script>
+</body>
+</html>
+`))
+
+//Viewer writes a html page rendering document at url with Redoc
+func Viewer(w io.Writer, title, url string) error {
+	return redoc.Execute(w, struct{ Title, URL, Script string }{title, url, RedocScript})
+}
@@ -0,0 +1,30 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestViewer(t *testing.T) {
+
+	var page strings.Builder
+	if err := Viewer(&page, "API", "/docs/openapi.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(page.String(), `<script src="`+RedocScript+`"></script>`) || strings.Contains(page.String(), "latest") {
+		t.Errorf("Viewer loads %s", page.String())
+	}
+
+	defer func(script string) { RedocScript = script }(RedocScript)
+	RedocScript = "/static/redoc.standalone.js"
+
+	page.Reset()
+	if err := Viewer(&page, "API", "/docs/openapi.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if script := `<script src="/static/redoc.standalone.js"></script>`; !strings.Contains(page.String(), script) {
+		t.Errorf("Viewer does not load %s in %s", script, page.String())
+	}
+}
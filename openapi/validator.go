@@ -0,0 +1,605 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/log"
+	"github.com/raythorn/zebra/router"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	templateRegex = regexp.MustCompile(`\{([^}/]+)\}`)
+	uuidRegex     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+//FieldError is an invalid field of request or response
+type FieldError struct {
+	//In is where the field is, "path", "query", "header", "cookie", "body" or "response"
+	In string `json:"in"`
+
+	//Name is name of param, or path of field in body, such as "friends.0.name"
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+//Validator validates requests against operations in document before handler, requests to
+//paths or methods not in document are passed
+type Validator struct {
+	doc   *Document
+	paths []*pathTemplate
+
+	//Responses enables validating responses, invalid response is logged and replaced with
+	//500 and errors, it's for development
+	Responses bool
+
+	patterns sync.Map
+}
+
+//pathTemplate matches request path with a path in document
+type pathTemplate struct {
+	regexp  *regexp.Regexp
+	names   []string
+	statics int
+	item    *PathItem
+}
+
+//NewValidator creates a Validator of document
+func NewValidator(doc *Document) *Validator {
+
+	v := &Validator{doc: doc}
+
+	for path, item := range doc.Paths {
+		t := &pathTemplate{item: item}
+		exp := "^"
+		last := 0
+		for _, loc := range templateRegex.FindAllStringSubmatchIndex(path, -1) {
+			exp += regexp.QuoteMeta(path[last:loc[0]]) + "([^/]+)"
+			t.names = append(t.names, path[loc[2]:loc[3]])
+			t.statics += loc[0] - last
+			last = loc[1]
+		}
+		exp += regexp.QuoteMeta(path[last:]) + "$"
+		t.statics += len(path) - last
+		t.regexp = regexp.MustCompile(exp)
+
+		v.paths = append(v.paths, t)
+	}
+
+	// Path with more static text is more specific
+	sort.SliceStable(v.paths, func(i, j int) bool {
+		return v.paths[i].statics > v.paths[j].statics
+	})
+
+	return v
+}
+
+//Midware validates requests, it's used with Use of router
+func (v *Validator) Midware() router.Midware {
+	return func(ctx *context.Context) bool {
+		op, values := v.operation(ctx.Request())
+		if op == nil {
+			return true
+		}
+
+		if errs := v.ValidateRequest(ctx, op, values); len(errs) > 0 {
+			respond(ctx, http.StatusBadRequest, "Invalid request", errs)
+			return false
+		}
+
+		return true
+	}
+}
+
+//Middleware validates requests, and responses if Responses enabled, it's used with Wrap of
+//router, or Use of group and route
+func (v *Validator) Middleware() router.Middleware {
+	return func(ctx *context.Context, next func()) {
+		op, values := v.operation(ctx.Request())
+		if op == nil {
+			next()
+			return
+		}
+
+		if errs := v.ValidateRequest(ctx, op, values); len(errs) > 0 {
+			respond(ctx, http.StatusBadRequest, "Invalid request", errs)
+			return
+		}
+
+		if !v.Responses {
+			next()
+			return
+		}
+
+		c := buffer(ctx, next)
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+
+		if errs := v.ValidateResponse(op, c.code, c.body.Bytes()); len(errs) > 0 {
+			log.Error("OpenAPI: invalid response of %s %s: %v", ctx.Method(), ctx.URL(), errs)
+			respond(ctx, http.StatusInternalServerError, "Invalid response", errs)
+			return
+		}
+
+		c.flush()
+	}
+}
+
+//buffer calls next with response captured, on panic response of ctx.Intercept is written
+//as it is, and response of other panic is discarded for recovery of router
+func buffer(ctx *context.Context, next func()) *capture {
+	rw := ctx.ResponseWriter()
+	c := &capture{ResponseWriter: rw}
+	ctx.Replace(c, ctx.Request())
+	defer ctx.Replace(rw, ctx.Request())
+
+	defer func() {
+		if err := recover(); err != nil {
+			if _, ok := err.(*context.Aborted); ok {
+				c.flush()
+			}
+			panic(err)
+		}
+	}()
+
+	next()
+	return c
+}
+
+//operation returns operation for request with values of path params, nil if not in document
+func (v *Validator) operation(req *http.Request) (*Operation, map[string]string) {
+
+	path := req.URL.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	for _, t := range v.paths {
+		matches := t.regexp.FindStringSubmatch(path)
+		if matches == nil {
+			continue
+		}
+
+		//A less specific template may have the method, e.g. /users/{id} for /users/me
+		op, ok := (*t.item)[strings.ToLower(req.Method)]
+		if !ok {
+			continue
+		}
+
+		values := make(map[string]string, len(t.names))
+		for i, name := range t.names {
+			values[name] = matches[i+1]
+		}
+
+		return op, values
+	}
+
+	return nil, nil
+}
+
+//ValidateRequest validates params and json body of request against operation, values are
+//path params
+func (v *Validator) ValidateRequest(ctx *context.Context, op *Operation, values map[string]string) []FieldError {
+
+	errs := []FieldError{}
+	req := ctx.Request()
+	query := req.URL.Query()
+
+	for _, param := range op.Parameters {
+		var raw []string
+		switch param.In {
+		case "path":
+			if value, ok := values[param.Name]; ok {
+				raw = []string{value}
+			}
+		case "query":
+			raw = query[param.Name]
+		case "header":
+			if value := req.Header.Get(param.Name); value != "" {
+				raw = []string{value}
+			}
+		case "cookie":
+			if cookie, err := req.Cookie(param.Name); err == nil {
+				raw = []string{cookie.Value}
+			}
+		}
+
+		if len(raw) == 0 {
+			if param.Required || param.In == "path" {
+				errs = append(errs, FieldError{In: param.In, Name: param.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		schema := v.resolve(param.Schema)
+		value, err := coerce(schema, raw)
+		if err != nil {
+			errs = append(errs, FieldError{In: param.In, Name: param.Name, Message: err.Error()})
+			continue
+		}
+
+		v.validate(schema, value, param.In, param.Name, &errs)
+	}
+
+	if op.RequestBody != nil {
+		body := ctx.Body()
+		if len(bytes.TrimSpace(body)) == 0 {
+			if op.RequestBody.Required {
+				errs = append(errs, FieldError{In: "body", Message: "is required"})
+			}
+		} else if schema := jsonSchema(op.RequestBody.Content); schema != nil {
+			v.validateJSON(schema, body, "body", &errs)
+		}
+	}
+
+	return errs
+}
+
+//ValidateResponse validates status code and json body of response against operation
+func (v *Validator) ValidateResponse(op *Operation, code int, body []byte) []FieldError {
+
+	errs := []FieldError{}
+	status := strconv.Itoa(code)
+
+	response, ok := op.Responses[status]
+	if !ok {
+		response, ok = op.Responses[status[:1]+"XX"]
+	}
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+
+	if !ok {
+		return append(errs, FieldError{In: "response", Message: "status " + status + " is not documented"})
+	}
+
+	if schema := jsonSchema(response.Content); schema != nil && len(bytes.TrimSpace(body)) > 0 {
+		v.validateJSON(schema, body, "response", &errs)
+	}
+
+	return errs
+}
+
+func (v *Validator) validateJSON(schema *Schema, body []byte, in string, errs *[]FieldError) {
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		*errs = append(*errs, FieldError{In: in, Message: "invalid json: " + err.Error()})
+		return
+	}
+
+	v.validate(schema, value, in, "", errs)
+}
+
+//jsonSchema returns schema of json media type in content, application/json first and
+//then other json media types in sorted order
+func jsonSchema(content map[string]*MediaType) *Schema {
+	if media := content["application/json"]; media != nil {
+		return media.Schema
+	}
+
+	mimes := make([]string, 0, len(content))
+	for mime, media := range content {
+		if strings.Contains(mime, "json") && media != nil {
+			mimes = append(mimes, mime)
+		}
+	}
+
+	if len(mimes) == 0 {
+		return nil
+	}
+
+	sort.Strings(mimes)
+	return content[mimes[0]].Schema
+}
+
+//resolve follows reference of schema in components
+func (v *Validator) resolve(schema *Schema) *Schema {
+	for i := 0; schema != nil && schema.Ref != "" && i < 32; i++ {
+		schema = v.doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+
+	return schema
+}
+
+//validate validates value decoded from json against schema, and adds errors with name
+func (v *Validator) validate(schema *Schema, value interface{}, in, name string, errs *[]FieldError) {
+
+	schema = v.resolve(schema)
+	if schema == nil {
+		return
+	}
+
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{In: in, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if schema.never {
+		fail("is not allowed")
+		return
+	}
+
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, in, name, errs)
+	}
+
+	if len(schema.AnyOf) > 0 && v.matches(schema.AnyOf, value) == 0 {
+		fail("must match any of schemas")
+	}
+
+	if len(schema.OneOf) > 0 && v.matches(schema.OneOf, value) != 1 {
+		fail("must match exactly one of schemas")
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			fail("must not be null")
+		}
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, item := range schema.Enum {
+			if fmt.Sprint(item) == fmt.Sprint(value) {
+				found = true
+			}
+		}
+
+		if !found {
+			fail("must be one of %v", schema.Enum)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be object")
+			return
+		}
+
+		for _, key := range schema.Required {
+			if _, ok := object[key]; !ok {
+				*errs = append(*errs, FieldError{In: in, Name: join(name, key), Message: "is required"})
+			}
+		}
+
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if property, ok := schema.Properties[key]; ok {
+				v.validate(property, object[key], in, join(name, key), errs)
+			} else if schema.AdditionalProperties != nil {
+				v.validate(schema.AdditionalProperties, object[key], in, join(name, key), errs)
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			fail("must be array")
+			return
+		}
+
+		if schema.MinItems != nil && len(list) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+
+		if schema.MaxItems != nil && len(list) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+
+		for i, item := range list {
+			v.validate(schema.Items, item, in, join(name, strconv.Itoa(i)), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("must be string")
+			return
+		}
+
+		length := utf8.RuneCountInString(s)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("must be at least %d characters", *schema.MinLength)
+		}
+
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("must be at most %d characters", *schema.MaxLength)
+		}
+
+		if schema.Pattern != "" && !v.pattern(schema.Pattern).MatchString(s) {
+			fail("must match %s", schema.Pattern)
+		}
+
+		if msg := format(schema.Format, s); msg != "" {
+			fail("%s", msg)
+		}
+	case "integer", "number":
+		n, ok := number(value)
+		if !ok {
+			fail("must be %s", schema.Type)
+			return
+		}
+
+		if schema.Type == "integer" && n != float64(int64(n)) {
+			fail("must be integer")
+			return
+		}
+
+		if schema.Minimum != nil && n < *schema.Minimum {
+			fail("must be at least %v", *schema.Minimum)
+		}
+
+		if schema.Maximum != nil && n > *schema.Maximum {
+			fail("must be at most %v", *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be boolean")
+		}
+	}
+}
+
+//matches returns count of schemas value matches
+func (v *Validator) matches(schemas []*Schema, value interface{}) int {
+	count := 0
+	for _, schema := range schemas {
+		errs := []FieldError{}
+		if v.validate(schema, value, "", "", &errs); len(errs) == 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+//pattern returns compiled regexp of pattern, and caches it
+func (v *Validator) pattern(pattern string) *regexp.Regexp {
+	if exp, ok := v.patterns.Load(pattern); ok {
+		return exp.(*regexp.Regexp)
+	}
+
+	exp, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Error("OpenAPI: invalid pattern %s: %s", pattern, err)
+		exp = regexp.MustCompile(".*")
+	}
+
+	v.patterns.Store(pattern, exp)
+	return exp
+}
+
+//coerce converts raw values of param into the type of schema
+func coerce(schema *Schema, raw []string) (interface{}, error) {
+
+	if schema == nil {
+		return raw[0], nil
+	}
+
+	switch schema.Type {
+	case "array":
+		if len(raw) == 1 {
+			raw = strings.Split(raw[0], ",")
+		}
+
+		list := make([]interface{}, 0, len(raw))
+		for _, item := range raw {
+			value, err := coerce(schema.Items, []string{item})
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case "integer":
+		if _, err := strconv.ParseInt(raw[0], 10, 64); err != nil {
+			return nil, fmt.Errorf("must be integer")
+		}
+		return json.Number(raw[0]), nil
+	case "number":
+		if _, err := strconv.ParseFloat(raw[0], 64); err != nil {
+			return nil, fmt.Errorf("must be number")
+		}
+		return json.Number(raw[0]), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw[0])
+		if err != nil {
+			return nil, fmt.Errorf("must be boolean")
+		}
+		return b, nil
+	}
+
+	return raw[0], nil
+}
+
+//number returns numeric value decoded from json
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+//format checks string with format, empty returns if valid or format unknown
+func format(name, s string) string {
+	switch name {
+	case "uuid":
+		if !uuidRegex.MatchString(s) {
+			return "must be uuid"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "must be date-time"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return "must be date"
+		}
+	}
+
+	return ""
+}
+
+func join(name, key string) string {
+	if name == "" {
+		return key
+	}
+
+	return name + "." + key
+}
+
+//respond writes status code with errors in json
+func respond(ctx *context.Context, code int, msg string, errs []FieldError) {
+	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	ctx.WriteHeader(code)
+	ctx.JSON(map[string]interface{}{"code": code, "msg": msg, "errors": errs}, false)
+}
+
+//capture buffers response for validating, headers are written to the underlying writer
+type capture struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (c *capture) WriteHeader(code int) {
+	if c.code == 0 {
+		c.code = code
+	}
+}
+
+func (c *capture) Write(data []byte) (int, error) {
+	return c.body.Write(data)
+}
+
+//flush writes captured response to the underlying writer
+func (c *capture) flush() {
+	if c.code == 0 {
+		c.code = http.StatusOK
+	}
+
+	c.ResponseWriter.WriteHeader(c.code)
+	c.ResponseWriter.Write(c.body.Bytes())
+}
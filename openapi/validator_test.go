@@ -0,0 +1,215 @@
+package openapi
+
+import (
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/router"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const spec = `
+openapi: 3.0.3
+info: {title: Users, version: "1.0"}
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer, minimum: 1}}
+    get:
+      parameters:
+        - {name: fields, in: query, schema: {type: array, items: {type: string, enum: [name, email]}}}
+        - {name: X-Tenant, in: header, required: true, schema: {type: string, format: uuid}}
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/User"}
+      responses:
+        "204": {description: Updated}
+components:
+  schemas:
+    User:
+      type: object
+      required: [name]
+      additionalProperties: false
+      properties:
+        name: {type: string, minLength: 2}
+        age: {type: integer, minimum: 0}
+        tags: {type: array, maxItems: 2, items: {type: string}}
+`
+
+func TestValidator(t *testing.T) {
+
+	doc, err := Load([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewValidator(doc)
+	v.Responses = true
+
+	r := router.New()
+	r.Wrap(v.Middleware())
+	r.Get("/users/:id", func(ctx *context.Context) {
+		if ctx.Get("id") == "2" {
+			ctx.JSON(map[string]interface{}{"name": 2}, false)
+			return
+		}
+		ctx.JSON(map[string]interface{}{"name": "ray"}, false)
+	})
+	r.Put("/users/:id", func(ctx *context.Context) { ctx.WriteHeader(http.StatusNoContent) })
+	r.Get("/health", func(ctx *context.Context) { ctx.WriteString("ok") })
+
+	tenant := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	tests := []struct {
+		method, path, tenant, body string
+		code                       int
+		errors                     []string
+	}{
+		{"GET", "/users/1?fields=name,email", tenant, "", 200, nil},
+		{"GET", "/users/0?fields=phone", "x", "", 400, []string{`"name":"id"`, `"name":"fields.0"`, `"name":"X-Tenant"`}},
+		{"GET", "/users/abc", tenant, "", 400, []string{`"must be integer"`}},
+		{"GET", "/users/2", tenant, "", 500, []string{`"in":"response","name":"name"`}},
+		{"PUT", "/users/1", "", `{"name":"ray","age":3,"tags":["a"]}`, 204, nil},
+		{"PUT", "/users/1", "", "", 400, []string{`"in":"body","message":"is required"`}},
+		{"PUT", "/users/1", "", `{"name":"r","age":-1,"tags":["a","b",1],"x":1}`, 400,
+			[]string{`"name":"name"`, `"name":"age"`, `"name":"tags"`, `"name":"tags.2"`, `"name":"x"`}},
+		{"PUT", "/users/1", "", `{"age":"3"}`, 400, []string{`"name":"name","message":"is required"`, `"must be integer"`}},
+		{"PUT", "/users/1", "", `{`, 400, []string{`invalid json`}},
+		{"GET", "/health", "", "", 200, nil},
+	}
+
+	for _, test := range tests {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.tenant != "" {
+			req.Header.Set("X-Tenant", test.tenant)
+		}
+		r.Handle(rw, req)
+
+		if rw.Code != test.code {
+			t.Errorf("%s %s: code %d, want %d: %s", test.method, test.path, rw.Code, test.code, rw.Body)
+		}
+
+		for _, e := range test.errors {
+			if !strings.Contains(rw.Body.String(), e) {
+				t.Errorf("%s %s: %s not in %s", test.method, test.path, e, rw.Body)
+			}
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+
+	doc, err := Load([]byte(`
+openapi: 3.0.3
+info: {title: Users, version: "1.0"}
+paths:
+  /users/me:
+    get:
+      responses: {"200": {description: OK}}
+  /users/{id}:
+    get:
+      responses: {"200": {description: OK}}
+    delete:
+      operationId: deleteUser
+      responses: {"204": {description: Deleted}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewValidator(doc)
+
+	op, values := v.operation(httptest.NewRequest("DELETE", "/users/me", nil))
+	if op == nil || op.OperationID != "deleteUser" || values["id"] != "me" {
+		t.Errorf("DELETE /users/me: operation %v, values %v, want deleteUser of /users/{id}", op, values)
+	}
+
+	if op, _ := v.operation(httptest.NewRequest("POST", "/users/me", nil)); op != nil {
+		t.Errorf("POST /users/me: operation %v, want nil", op)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+
+	problem, vendor, json := &Schema{Type: "object"}, &Schema{Type: "array"}, &Schema{Type: "string"}
+
+	content := map[string]*MediaType{
+		"application/problem+json":  {Schema: problem},
+		"application/vnd.user+json": {Schema: vendor},
+		"text/plain":                {Schema: &Schema{}},
+	}
+
+	for i := 0; i < 10; i++ {
+		if schema := jsonSchema(content); schema != problem {
+			t.Fatalf("jsonSchema = %v, want schema of application/problem+json", schema)
+		}
+	}
+
+	content["application/json"] = &MediaType{Schema: json}
+	if schema := jsonSchema(content); schema != json {
+		t.Errorf("jsonSchema = %v, want schema of application/json", schema)
+	}
+
+	if schema := jsonSchema(map[string]*MediaType{"text/plain": {Schema: json}}); schema != nil {
+		t.Errorf("jsonSchema = %v, want nil", schema)
+	}
+}
+
+func TestValidatorPanic(t *testing.T) {
+
+	doc, err := Load([]byte(`
+openapi: 3.0.3
+info: {title: Users, version: "1.0"}
+paths:
+  /panic:
+    get:
+      responses: {"200": {description: OK}}
+  /intercept:
+    get:
+      responses: {"200": {description: OK}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewValidator(doc)
+	v.Responses = true
+
+	r := router.New()
+	r.Wrap(v.Middleware())
+	r.Get("/panic", func(ctx *context.Context) {
+		ctx.WriteString("partial")
+		panic("handler fails")
+	})
+	r.Get("/intercept", func(ctx *context.Context) {
+		ctx.Intercept([]byte("denied"), http.StatusForbidden, "denied")
+	})
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/panic", 500, "500 internal server error\n"},
+		{"/intercept", 403, "denied"},
+	}
+
+	for _, c := range cases {
+		rw := httptest.NewRecorder()
+		r.Handle(rw, httptest.NewRequest("GET", c.path, nil))
+
+		if rw.Code != c.code || rw.Body.String() != c.body {
+			t.Errorf("GET %s = %d %q, want %d %q", c.path, rw.Code, rw.Body, c.code, c.body)
+		}
+	}
+}
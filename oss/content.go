@@ -109,21 +109,47 @@ func DepositContent(ctx *context.Context) {
 		return
 	}
 
-	data := ctx.Body()
-	var datalen int64 = int64(len(data))
-
-	log.Debug("Chunk: %d, Size: %d", chunk, datalen)
-	if chunk != datalen {
+	//Chunk is streamed into cache file, without holding it in memory
+	if length := ctx.Request().ContentLength; length >= 0 && length != chunk {
+		log.Debug("Chunk: %d, Size: %d", chunk, length)
 		ctx.WriteHeader(HTTP_REQUEST)
 		return
 	}
 
-	size, err := cache.WriteAt(data, from)
-	if size != len(data) || err != nil {
+	if _, err := cache.Seek(from, io.SeekStart); err != nil {
 		ctx.WriteHeader(HTTP_INTERNAL)
 		return
 	}
 
+	body := ctx.BodyReader()
+	defer body.Close()
+
+	//No more than chunk is written, one more byte is probed but not written
+	datalen, err := io.CopyN(cache, body, chunk)
+	if err == nil {
+		var probe [1]byte
+		var n int
+		n, err = io.ReadFull(body, probe[:])
+		datalen += int64(n)
+	}
+
+	log.Debug("Chunk: %d, Size: %d", chunk, datalen)
+	if (err != nil && err != io.EOF) || chunk != datalen {
+		//Range written is invalid, and it's uploaded again from start of the chunk
+		if e := cache.Truncate(from); e != nil {
+			log.Error("Truncate %s: %v", cachefile, e)
+		}
+
+		if err == context.ErrBodyTooLarge {
+			ctx.WriteHeader(http.StatusRequestEntityTooLarge)
+		} else if err != nil && err != io.EOF {
+			ctx.WriteHeader(HTTP_INTERNAL)
+		} else {
+			ctx.WriteHeader(HTTP_REQUEST)
+		}
+		return
+	}
+
 	if (to + 1) == length {
 		md5str := md5sum(cache)
 		filename := strings.TrimSuffix(path.Base(respath), ext)
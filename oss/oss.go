@@ -25,6 +25,9 @@ const (
 const (
 	//relative path of current file
 	OssPathKey = "com.raythorn.falcon.oss.path"
+
+	//path of object matched by catch-all of route, such as "css/main.css" for "/static/*file"
+	OssFileKey = "com.raythorn.falcon.oss.file"
 )
 
 // OSS archive manager, which can arrange objects path with your own algrithem
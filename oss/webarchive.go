@@ -2,12 +2,21 @@ package oss
 
 import (
 	"github.com/raythorn/zebra/context"
+	"path"
 )
 
+//WebArchive serves files under root with the same path as matched by catch-all of route,
+//such as router.Oss("/static/*file", "www", &oss.WebArchive{})
 type WebArchive struct {
 }
 
 func (web *WebArchive) Path(oss *Oss, ctx *context.Context) string {
 
-	return ""
+	file := ctx.Get(OssFileKey)
+	if file == "" {
+		return ""
+	}
+
+	//Clean with leading "/" to keep path inside root
+	return path.Join(oss.Root(), path.Clean("/"+file))
 }
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"strings"
+)
+
+// RouteDoc describes a route of one method for API document
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Request is a value of request body type, such as User{}, nil if no body
+	Request interface{}
+
+	// Responses are values of response body types with status codes, nil value means
+	// response without body
+	Responses map[int]interface{}
+}
+
+// ParamInfo describes a param in path of route
+type ParamInfo struct {
+	Name string `json:"name"`
+
+	// Rule is the constraint as written in pattern, such as "int", "uuid", "enum(a|b)"
+	// or a regexp, empty if not constrained
+	Rule string `json:"rule,omitempty"`
+
+	// Pattern is the regexp value matches, empty if any value in segment matches
+	Pattern string `json:"pattern,omitempty"`
+
+	// CatchAll is true if value is the rest of path
+	CatchAll bool `json:"catchall,omitempty"`
+}
+
+// Doc sets document of methods registered with route, such as
+//
+//	zebra.Get("/users/:id", show).Doc(router.RouteDoc{Summary: "Show user", Responses: map[int]interface{}{200: User{}}})
+func (r *Route) Doc(doc RouteDoc) *Route {
+	r.doc = &doc
+	return r
+}
+
+// describe returns pattern of route in OpenAPI form, such as "/users/{id}", and params
+// in it, regexp without exactly one named group is kept as it is
+func (r *Route) describe() (string, []ParamInfo) {
+
+	path := ""
+	params := []ParamInfo{}
+	param := -1
+
+	for _, tok := range r.tokens {
+		if tok.kind != tokenStatic {
+			param++
+		}
+
+		switch tok.kind {
+		case tokenStatic:
+			path += tok.text
+		case tokenParam:
+			path += "{" + tok.name + "}"
+			params = append(params, ParamInfo{Name: tok.name, Rule: tok.rule, Pattern: tok.constraint})
+		case tokenCatchAll:
+			path += "{" + tok.name + "}"
+			params = append(params, ParamInfo{Name: tok.name, CatchAll: true})
+		case tokenRegexp:
+			names := []string{}
+			for _, name := range r.params[param].regexp.SubexpNames() {
+				if name != "" {
+					names = append(names, name)
+				}
+			}
+
+			if len(names) != 1 {
+				path += tok.text
+				continue
+			}
+
+			path += "{" + names[0] + "}"
+			params = append(params, ParamInfo{Name: names[0], Rule: tok.text, Pattern: tok.text})
+		}
+	}
+
+	return path, params
+}
+
+// join joins prefix and path of route without duplicated "/"
+func join(prefix, path string) string {
+	if prefix == "" {
+		return path
+	}
+
+	return strings.TrimSuffix(prefix, "/") + path
+}
@@ -2,15 +2,24 @@ package router
 
 import (
 	"github.com/raythorn/zebra/context"
-	"strings"
+	"github.com/raythorn/zebra/log"
+	"sort"
+	"sync"
+	"sync/atomic"
 )
 
 type Group struct {
-	pattern string
-	routes  map[string]*Route
-	groups  map[string]*Group
-	before  []Midware
-	after   []Midware
+	pattern     string
+	routes      map[string]*Route
+	groups      map[string]*Group
+	parent      *Group
+	before      []Midware
+	after       []Midware
+	middlewares []Middleware
+
+	// tree is built from routes on first match after routes changed
+	mutex sync.Mutex
+	tree  atomic.Value
 }
 
 func newGroup() *Group {
@@ -34,8 +43,16 @@ func (g *Group) Before(midwares ...Midware) *Group {
 	return g
 }
 
+// Use adds middlewares wrapping handlers of all routes in this group and sub-groups,
+// middlewares of parent group wrap the ones of sub-group
+func (g *Group) Use(middlewares ...Middleware) *Group {
+	g.middlewares = append(g.middlewares, middlewares...)
+	return g
+}
+
 // After set midwares which will be called after actually http handler.
-// All routes in this group will be affected if set
+// All routes in this group will be affected if set, they're called after the rest of
+// chain inside this group returns, even if it's intercepted by a sub-group
 func (g *Group) After(midwares ...Midware) *Group {
 	if g.after == nil {
 		g.after = make([]Midware, 0)
@@ -85,19 +102,20 @@ func (g *Group) Any(pattern string, handler Handler) *Route {
 
 func (g *Group) group(pattern string, args ...interface{}) *Group {
 
+	defer g.invalidate()
+
 	for _, arg := range args {
 		switch arg.(type) {
 		case *Route:
 			route, _ := arg.(*Route)
-			route.pattern = cleanPath(pattern + route.pattern)
-			route.regexpCompile()
-			route.group = g
+			route.each(func(rt *Route) {
+				rt.pattern = cleanPath(pattern + rt.pattern)
+				rt.compile()
+				rt.group = g
+			})
 
 			if r, ok := g.routes[route.pattern]; ok {
-				for m, h := range route.actions {
-					r.actions[m] = h
-				}
-
+				r.merge(route)
 				route = nil
 			} else {
 				g.routes[route.pattern] = route
@@ -105,13 +123,21 @@ func (g *Group) group(pattern string, args ...interface{}) *Group {
 		case *Group:
 			grp, _ := arg.(*Group)
 			grp.pattern = cleanPath(pattern + grp.pattern)
+			grp.parent = g
 			g.groups[grp.pattern] = grp
 
 			if len(grp.routes) > 0 {
 				for _, route := range grp.routes {
-					route.pattern = cleanPath(pattern + route.pattern)
-					route.regexpCompile()
-					g.routes[route.pattern] = route
+					route.each(func(rt *Route) {
+						rt.pattern = cleanPath(pattern + rt.pattern)
+						rt.compile()
+					})
+
+					if r, ok := g.routes[route.pattern]; ok && r != route {
+						r.merge(route)
+					} else {
+						g.routes[route.pattern] = route
+					}
 				}
 			}
 
@@ -119,19 +145,6 @@ func (g *Group) group(pattern string, args ...interface{}) *Group {
 				for _, group := range grp.groups {
 					group.pattern = cleanPath(pattern + group.pattern)
 					g.groups[group.pattern] = group
-					if len(grp.before) > 0 {
-						if group.before == nil {
-							group.before = make([]Midware, 0)
-						}
-						group.before = append(grp.before, group.before...)
-					}
-					if len(grp.after) > 0 {
-						if group.after == nil {
-							group.after = make([]Midware, 0)
-						}
-
-						group.after = append(group.after, grp.after...)
-					}
 				}
 			}
 		}
@@ -144,52 +157,140 @@ func (g *Group) add(method, pattern string, handler Handler) *Route {
 	route := newRoute()
 	route.pattern = cleanPath(pattern)
 	route.actions[method] = handler
-	route.regexpCompile()
+	route.handles[method] = route
+	route.compile()
 
 	return route
 }
 
 func (g *Group) insert(method, pattern string, handler Handler) *Route {
+	return g.put(g.add(method, pattern, handler))
+}
 
-	route := newRoute()
+// put adds route into g, and returns it for setting methods registered with it, route
+// is merged into the one with the same pattern if any
+func (g *Group) put(route *Route) *Route {
 
-	route.pattern = cleanPath(pattern)
-	route.actions[method] = handler
-	route.regexpCompile()
+	defer g.invalidate()
 
 	if rt, ok := g.routes[route.pattern]; ok {
-		for m, h := range route.actions {
-			rt.actions[m] = h
-		}
+		rt.merge(route)
+	} else {
+		g.routes[route.pattern] = route
+	}
+
+	return route
+}
 
-		if route.oss != nil {
-			rt.oss = route.oss
+// mount adds routes of group into g, g is the lookup table of all groups, and keeps
+// routes belong to their own group
+func (g *Group) mount(group *Group) {
+
+	defer g.invalidate()
+
+	for pattern, route := range group.routes {
+		if rt, ok := g.routes[pattern]; ok && rt != route {
+			rt.merge(route)
+			continue
 		}
 
-		route = nil
-		return rt
-	} else {
-		g.routes[route.pattern] = route
-		return route
+		g.routes[pattern] = route
 	}
 }
 
-func (g *Group) match(ctx *context.Context) *Route {
+// chain returns middlewares of route from the outermost group to the innermost one,
+// midwares set by Before and After run around the rest of chain inside middlewares
+// of the same group
+func (g *Group) chain(middlewares []Middleware) []Middleware {
+	if g == nil {
+		return middlewares
+	}
+
+	middlewares = g.parent.chain(middlewares)
+	middlewares = append(middlewares, g.middlewares...)
 
-	if r, ok := g.routes[ctx.URL()]; ok {
-		if r.match(ctx) {
-			return r
+	if len(g.before) == 0 && len(g.after) == 0 {
+		return middlewares
+	}
+
+	return append(middlewares, func(ctx *context.Context, next func()) {
+		for _, midware := range g.before {
+			if !midware(ctx) {
+				return
+			}
 		}
-	} else {
 
-		for p, r := range g.routes {
-			if strings.Contains(p, "(?P") {
-				if r.match(ctx) {
-					return r
-				}
+		next()
+
+		for _, midware := range g.after {
+			if !midware(ctx) {
+				return
 			}
 		}
+	})
+}
+
+// invalidate drops the tree, it will be rebuilt on next match
+func (g *Group) invalidate() {
+	g.tree.Store((*node)(nil))
+}
+
+// lookup returns the radix tree of routes, and builds it if routes changed
+func (g *Group) lookup() *node {
+
+	if tree, ok := g.tree.Load().(*node); ok && tree != nil {
+		return tree
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if tree, ok := g.tree.Load().(*node); ok && tree != nil {
+		return tree
+	}
+
+	tree, conflicts := g.build()
+	for _, conflict := range conflicts {
+		log.Warning("%s, ignored", conflict)
+	}
+
+	g.tree.Store(tree)
+	return tree
+}
+
+// build builds radix tree of routes in order of their patterns, so the tree is
+// always the same for the same routes, and returns the routes can't be added as
+// conflicts
+func (g *Group) build() (*node, []string) {
+
+	patterns := make([]string, 0, len(g.routes))
+	for pattern := range g.routes {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	tree := &node{}
+	conflicts := []string{}
+	for _, pattern := range patterns {
+		route := g.routes[pattern]
+		if exist := tree.insert(route.tokens, route); exist != route {
+			conflicts = append(conflicts, "Route "+route.pattern+" conflicts with "+exist.pattern)
+		}
+	}
+
+	return tree, conflicts
+}
+
+// match finds route for path regardless of method, and returns it with wildcard
+// values. A trailing slash in path is optional.
+func (g *Group) match(path string) (*Route, []string) {
+
+	tree := g.lookup()
+
+	route, values := tree.find(path, nil)
+	if route == nil && len(path) > 1 && path[len(path)-1] == '/' {
+		route, values = tree.find(path[:len(path)-1], nil)
 	}
 
-	return nil
+	return route, values
 }
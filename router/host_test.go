@@ -0,0 +1,55 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+
+	var table, tenant string
+	served := func(name string) Handler {
+		return func(ctx *context.Context) {
+			table, tenant = name, ctx.Get("tenant")
+		}
+	}
+
+	r := New()
+	r.Get("/ping", served("default"))
+	r.Get("/about", served("default"))
+	r.Host("api.example.com", (&Group{}).Get("/ping", served("api")))
+	r.Host(":tenant.example.com", (&Group{}).Get("/ping", served("tenant")))
+
+	cases := []struct {
+		host   string
+		path   string
+		code   int
+		table  string
+		tenant string
+	}{
+		{"example.com", "/ping", http.StatusOK, "default", ""},
+		{"api.example.com", "/ping", http.StatusOK, "api", ""},
+		{"API.Example.com:8080", "/ping", http.StatusOK, "api", ""},
+		{"acme.example.com", "/ping", http.StatusOK, "tenant", "acme"},
+		{"other.org", "/about", http.StatusOK, "default", ""},
+
+		// Matched host is served by its own routes only
+		{"acme.example.com", "/about", http.StatusNotFound, "", ""},
+	}
+
+	for _, c := range cases {
+		table, tenant = "", ""
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Host = c.host
+		r.Handle(rw, req)
+
+		if rw.Code != c.code || table != c.table || tenant != c.tenant {
+			t.Errorf("GET %s%s = %d by %q tenant %q, want %d by %q tenant %q", c.host, c.path,
+				rw.Code, table, tenant, c.code, c.table, c.tenant)
+		}
+	}
+}
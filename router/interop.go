@@ -0,0 +1,70 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WrapHandler adapts a net/http handler to Handler, the request body is streamed to
+// handler unless already read by context
+func WrapHandler(handler http.Handler) Handler {
+	return func(ctx *context.Context) {
+		handler.ServeHTTP(ctx.ResponseWriter(), restore(ctx))
+	}
+}
+
+// WrapMiddleware adapts a net/http middleware to Middleware, the writer and request
+// passed to next by middleware replace the ones of context, until middleware returns
+func WrapMiddleware(middleware func(http.Handler) http.Handler) Middleware {
+	return func(ctx *context.Context, next func()) {
+		rw, req := ctx.ResponseWriter(), ctx.Request()
+		defer ctx.Replace(rw, req)
+
+		h := middleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			ctx.Replace(rw, req)
+			next()
+		}))
+
+		h.ServeHTTP(rw, restore(ctx))
+	}
+}
+
+// restore sets request body with the stream of context, which is read from memory if
+// read by Body, url-encoded form is encoded again as it's consumed by parsing form
+func restore(ctx *context.Context) *http.Request {
+
+	req := ctx.Request()
+
+	if len(req.PostForm) > 0 {
+		req.Body = ioutil.NopCloser(strings.NewReader(req.PostForm.Encode()))
+	} else {
+		req.Body = ctx.BodyReader()
+	}
+
+	return req
+}
+
+// mount returns handler serving request with handler, and prefix stripped from path
+func mount(prefix string, handler http.Handler) Handler {
+	return func(ctx *context.Context) {
+
+		req := restore(ctx)
+
+		path := strings.TrimPrefix(req.URL.Path, prefix)
+		if path == "" || path[0] != '/' {
+			path = "/" + path
+		}
+
+		r := new(http.Request)
+		*r = *req
+		r.URL = new(url.URL)
+		*r.URL = *req.URL
+		r.URL.Path = path
+		r.URL.RawPath = ""
+
+		handler.ServeHTTP(ctx.ResponseWriter(), r)
+	}
+}
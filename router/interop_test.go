@@ -0,0 +1,110 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+
+	var path, query, body string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(rw http.ResponseWriter, req *http.Request) {
+		data, _ := ioutil.ReadAll(req.Body)
+		path, query, body = req.URL.Path, req.URL.RawQuery, string(data)
+	})
+
+	r := New()
+	r.Mount("/mux/", mux)
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		path   string
+		query  string
+	}{
+		{"GET", "/mux", "", "/", ""},
+		{"GET", "/mux/a/b?x=1", "", "/a/b", "x=1"},
+		{"POST", "/mux/upload", "data", "/upload", ""},
+	}
+
+	for _, c := range cases {
+		path, query, body = "", "", ""
+		r.Handle(httptest.NewRecorder(), httptest.NewRequest(c.method, c.target, strings.NewReader(c.body)))
+
+		if path != c.path || query != c.query || body != c.body {
+			t.Errorf("%s %s served with %q %q %q, want %q %q %q", c.method, c.target,
+				path, query, body, c.path, c.query, c.body)
+		}
+	}
+}
+
+func TestWrapHandler(t *testing.T) {
+
+	r := New()
+	r.Post("/form", func(ctx *context.Context) {
+		WrapHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			// Form parsed by context is encoded again for handler
+			data, _ := ioutil.ReadAll(req.Body)
+			rw.Write(data)
+		}))(ctx)
+	})
+
+	req := httptest.NewRequest("POST", "/form", strings.NewReader("name=zebra"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rw := httptest.NewRecorder()
+	r.Handle(rw, req)
+
+	if rw.Body.String() != "name=zebra" {
+		t.Errorf("POST /form body %q, want name=zebra", rw.Body)
+	}
+}
+
+func TestWrapMiddleware(t *testing.T) {
+
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			req = req.WithContext(req.Context())
+			req.Header.Set("X-Wrapped", "yes")
+			rw.Header().Set("X-Wrapped", "yes")
+			next.ServeHTTP(rw, req)
+		})
+	}
+
+	recorder := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(httptest.NewRecorder(), req)
+		})
+	}
+
+	var wrapped string
+	var before, after *http.Request
+	var writer http.ResponseWriter
+	outer := func(ctx *context.Context, next func()) {
+		before = ctx.Request()
+		next()
+		after, writer = ctx.Request(), ctx.ResponseWriter()
+	}
+
+	r := New()
+	r.Get("/wrap", func(ctx *context.Context) {
+		wrapped = ctx.Request().Header.Get("X-Wrapped")
+	}).Use(outer, WrapMiddleware(header), WrapMiddleware(recorder))
+
+	rw := httptest.NewRecorder()
+	r.Handle(rw, httptest.NewRequest("GET", "/wrap", nil))
+
+	if wrapped != "yes" || rw.Header().Get("X-Wrapped") != "yes" {
+		t.Errorf("GET /wrap handler sees X-Wrapped %q, response %q", wrapped, rw.Header().Get("X-Wrapped"))
+	}
+
+	// Outer middleware sees its own writer and request after wrapped middlewares return
+	if after != before || writer != http.ResponseWriter(rw) {
+		t.Errorf("GET /wrap outer middleware sees request %p, writer %T after next", after, writer)
+	}
+}
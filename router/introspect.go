@@ -0,0 +1,159 @@
+package router
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// RouteInfo describes a route for one method
+type RouteInfo struct {
+	Method      string `json:"method"`
+	Pattern     string `json:"pattern"`
+	Name        string `json:"name,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Middlewares int    `json:"middlewares"`
+
+	// Path is the path served in OpenAPI form, such as "/api/v2/users/{id}"
+	Path   string      `json:"path"`
+	Params []ParamInfo `json:"params,omitempty"`
+	Doc    *RouteDoc   `json:"-"`
+}
+
+// table is a route table with its host or version
+type table struct {
+	group   *Group
+	host    string
+	version string
+}
+
+// tables returns all route tables, routes without host come first, then routes of
+// hosts and routes of versions
+func (r *router) tables() []table {
+	tables := []table{{group: r.group}, {group: r.route}}
+
+	patterns := make([]string, 0, len(r.hostTables))
+	for pattern := range r.hostTables {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		tables = append(tables, table{group: r.hostTables[pattern], host: pattern})
+	}
+
+	for _, v := range r.versions {
+		tables = append(tables, table{group: v.table, version: v.name})
+	}
+
+	return tables
+}
+
+func (r *router) Routes() []RouteInfo {
+
+	routes := []RouteInfo{}
+
+	for _, t := range r.tables() {
+		for _, route := range t.group.routes {
+			path, params := route.describe()
+			if route.version != nil {
+				path = route.version.path(path)
+			}
+
+			name := ""
+			if names := route.names(); len(names) > 0 {
+				name = names[0]
+			}
+
+			for method := range route.actions {
+				handle := route.handles[method]
+				count := len(r.wraps) + len(handle.middlewares)
+				prefix := ""
+				for group := handle.group; group != nil; group = group.parent {
+					count += len(group.middlewares) + len(group.before) + len(group.after)
+					if prefix == "" {
+						prefix = group.pattern
+					}
+				}
+
+				routes = append(routes, RouteInfo{
+					Method:      method,
+					Pattern:     route.pattern,
+					Name:        name,
+					Host:        t.host,
+					Version:     t.version,
+					Group:       prefix,
+					Middlewares: count,
+					Path:        path,
+					Params:      params,
+					Doc:         handle.doc,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Version != routes[j].Version {
+			return routes[i].Version < routes[j].Version
+		}
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+func (r *router) Validate() error {
+
+	problems := []string{}
+
+	for _, t := range r.tables() {
+		scope := ""
+		if t.host != "" || t.version != "" {
+			scope = t.host + t.version + ": "
+		}
+
+		_, conflicts := t.group.build()
+		for _, conflict := range conflicts {
+			problems = append(problems, scope+conflict)
+		}
+
+		for _, route := range t.group.routes {
+			for _, method := range route.duplicates {
+				problems = append(problems, scope+method+" "+route.pattern+" registered more than once")
+			}
+
+			if names := route.names(); len(names) > 1 {
+				problems = append(problems, scope+route.pattern+" named "+strings.Join(names, " and "))
+			}
+		}
+	}
+
+	// Group routes shadow routes with the same pattern and method
+	for pattern, route := range r.route.routes {
+		if shadow, ok := r.group.routes[pattern]; ok {
+			for method := range route.actions {
+				if _, ok := shadow.actions[method]; ok {
+					problems = append(problems, method+" "+pattern+" registered in group and router")
+				}
+			}
+		}
+	}
+
+	_, conflicts := r.hosts.build()
+	problems = append(problems, conflicts...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return errors.New("Router: ambiguous routes, " + strings.Join(problems, "; "))
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+
+	handler := func(ctx *context.Context) {}
+	pass := func(ctx *context.Context) bool { return true }
+
+	r := New()
+	r.Wrap(func(ctx *context.Context, next func()) { next() })
+	r.Get("/user/:id<int>", handler).Name("user.show")
+	r.Put("/user/:id<int>", handler)
+	r.Group("/api", (&Group{}).Get("/ping", handler)).Before(pass)
+	r.Host("api.example.com", (&Group{}).Get("/ping", handler))
+
+	routes := r.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("Routes() returns %d routes, want 4", len(routes))
+	}
+
+	// Routes without host come first, sorted by pattern and method
+	want := []RouteInfo{
+		{Method: "GET", Pattern: "/api/ping", Group: "/api", Middlewares: 2, Path: "/api/ping"},
+		{Method: "GET", Pattern: "/user/:id<int>", Name: "user.show", Middlewares: 1, Path: "/user/{id}"},
+		{Method: "PUT", Pattern: "/user/:id<int>", Name: "user.show", Middlewares: 1, Path: "/user/{id}"},
+		{Method: "GET", Pattern: "/ping", Host: "api.example.com", Group: "/", Middlewares: 1, Path: "/ping"},
+	}
+
+	for i, route := range routes {
+		w := want[i]
+		if route.Method != w.Method || route.Pattern != w.Pattern || route.Name != w.Name || route.Host != w.Host ||
+			route.Group != w.Group || route.Middlewares != w.Middlewares || route.Path != w.Path {
+			t.Errorf("Routes()[%d] = %+v, want %+v", i, route, w)
+		}
+	}
+
+	if params := routes[1].Params; len(params) != 1 || params[0].Name != "id" || params[0].Rule != "int" {
+		t.Errorf("params of /user/:id<int> = %+v", params)
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	handler := func(ctx *context.Context) {}
+
+	cases := []struct {
+		name    string
+		setup   func(r Router)
+		problem string
+	}{
+		{"conflict", func(r Router) {
+			r.Get("/user/:id", handler)
+			r.Get("/user/:name", handler)
+		}, "/user/:name conflicts"},
+		{"duplicate", func(r Router) {
+			r.Get("/user/:id", handler)
+			r.Get("/user/:id", handler)
+		}, "GET /user/:id registered more than once"},
+		{"shadow", func(r Router) {
+			r.Get("/user/:id", handler)
+			r.Group("/", (&Group{}).Get("/user/:id", handler))
+		}, "GET /user/:id registered in group and router"},
+		{"host", func(r Router) {
+			r.Host(":tenant.example.com", (&Group{}).Get("/ping", handler))
+			r.Host(":team.example.com", (&Group{}).Get("/ping", handler))
+		}, "Route :tenant.example.com conflicts with :team.example.com"},
+		{"name", func(r Router) {
+			r.Get("/user/:id", handler).Name("user.show")
+			r.Put("/user/:id", handler).Name("user.update")
+		}, "/user/:id named user.show and user.update"},
+		{"valid", func(r Router) {
+			r.Get("/user/:id", handler).Name("user")
+			r.Put("/user/:id", handler).Name("user")
+			r.Host("api.example.com", (&Group{}).Get("/user/:id", handler))
+		}, ""},
+	}
+
+	for _, c := range cases {
+		r := New()
+		c.setup(r)
+
+		err := r.Validate()
+		if c.problem == "" {
+			if err != nil {
+				t.Errorf("%s: Validate() = %s, want nil", c.name, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), c.problem) {
+			t.Errorf("%s: Validate() = %v, want %q", c.name, err, c.problem)
+		}
+	}
+}
+
+func TestRouteDoc(t *testing.T) {
+
+	handler := func(ctx *context.Context) {}
+
+	r := New()
+	get := r.Get("/user/:id", handler)
+	r.Put("/user/:id", handler).Doc(RouteDoc{Summary: "Update user"})
+	r.Delete("/user/:id", handler)
+	get.Doc(RouteDoc{Summary: "Show user"})
+
+	g := (&Group{}).Post("/users", handler).Doc(RouteDoc{Summary: "Create user"})
+	r.Group("/api", (&Group{}).Get("/users", handler).Doc(RouteDoc{Summary: "List users"}), g)
+
+	want := map[string]string{
+		"GET /user/:id":    "Show user",
+		"PUT /user/:id":    "Update user",
+		"DELETE /user/:id": "",
+		"GET /api/users":   "List users",
+		"POST /api/users":  "Create user",
+	}
+
+	for _, route := range r.Routes() {
+		summary := ""
+		if route.Doc != nil {
+			summary = route.Doc.Summary
+		}
+
+		if key := route.Method + " " + route.Pattern; summary != want[key] {
+			t.Errorf("document of %s = %q, want %q", key, summary, want[key])
+		}
+	}
+}
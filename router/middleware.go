@@ -0,0 +1,43 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+)
+
+// Middleware wraps handler, it calls next to run the rest of chain and handler, and
+// can do something before and after next, or not call next to intercept request.
+//
+//	func Timing(ctx *context.Context, next func()) {
+//		start := time.Now()
+//		next()
+//		log.Info("%s %s %s", ctx.Method(), ctx.URL(), time.Since(start))
+//	}
+type Middleware func(ctx *context.Context, next func())
+
+// Middleware adapts midware to Middleware, next is called only if midware returns true
+func (m Midware) Middleware() Middleware {
+	return func(ctx *context.Context, next func()) {
+		if m(ctx) {
+			next()
+		}
+	}
+}
+
+// chain runs middlewares in order, and handler at last
+func chain(ctx *context.Context, middlewares []Middleware, handler func()) {
+	i := 0
+
+	var next func()
+	next = func() {
+		if i < len(middlewares) {
+			m := middlewares[i]
+			i++
+			m(ctx, next)
+			return
+		}
+
+		handler()
+	}
+
+	next()
+}
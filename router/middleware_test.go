@@ -0,0 +1,129 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+
+	trace := []string{}
+	mark := func(name string, pass bool) Middleware {
+		return func(ctx *context.Context, next func()) {
+			trace = append(trace, name+">")
+			if pass {
+				next()
+			}
+			trace = append(trace, "<"+name)
+		}
+	}
+	handler := func() { trace = append(trace, "handler") }
+
+	chain(nil, []Middleware{mark("a", true), mark("b", true)}, handler)
+	if got := strings.Join(trace, " "); got != "a> b> handler <b <a" {
+		t.Errorf("chain trace %q", got)
+	}
+
+	trace = trace[:0]
+	chain(nil, []Middleware{mark("a", true), mark("b", false), mark("c", true)}, handler)
+	if got := strings.Join(trace, " "); got != "a> b> <b <a" {
+		t.Errorf("intercepted chain trace %q", got)
+	}
+
+	trace = trace[:0]
+	deny := Midware(func(ctx *context.Context) bool { return false })
+	chain(nil, []Middleware{deny.Middleware()}, handler)
+	if len(trace) != 0 {
+		t.Errorf("handler runs after midware returns false: %q", trace)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+
+	trace := []string{}
+	mark := func(name string) Middleware {
+		return func(ctx *context.Context, next func()) {
+			trace = append(trace, name+">")
+			next()
+			trace = append(trace, "<"+name)
+		}
+	}
+	midware := func(name string, pass bool) Midware {
+		return func(ctx *context.Context) bool {
+			trace = append(trace, name)
+			return pass
+		}
+	}
+
+	handler := func(ctx *context.Context) { trace = append(trace, "handler") }
+
+	r := New()
+	r.Wrap(mark("router"))
+	r.Group("/api",
+		grouped("/ping", handler).Use(mark("route")),
+		(&Group{}).Sub("/admin",
+			grouped("/users", handler),
+		).Use(mark("admin")).Before(midware("deny", false)),
+	).Use(mark("api")).Before(midware("before", true)).After(midware("after", true))
+	r.Group("/other", grouped("/ping", handler))
+
+	cases := []struct {
+		path  string
+		trace string
+	}{
+		{"/api/ping", "router> api> before route> handler <route after <api <router"},
+		{"/api/admin/users", "router> api> before admin> deny <admin after <api <router"},
+		{"/other/ping", "router> handler <router"},
+	}
+
+	for _, c := range cases {
+		trace = trace[:0]
+		serve(r, "GET", c.path)
+		if got := strings.Join(trace, " "); got != c.trace {
+			t.Errorf("GET %s trace %q, want %q", c.path, got, c.trace)
+		}
+	}
+}
+
+func TestMiddlewareOfMethod(t *testing.T) {
+
+	handler := func(ctx *context.Context) { ctx.WriteString("ok") }
+	deny := func(ctx *context.Context, next func()) { ctx.WriteHeader(401) }
+
+	r := New()
+	get := r.Get("/x", handler)
+	r.Post("/x", handler).Use(deny)
+	r.Put("/x", handler)
+	get.Use(func(ctx *context.Context, next func()) {
+		ctx.Header("X-Get", "1")
+		next()
+	})
+
+	// Routes of groups with the same pattern are merged, middlewares of route and group
+	// are kept for their own methods
+	r.Group("/g", (&Group{}).Get("/x", handler).Use(deny))
+	r.Group("/g", (&Group{}).Post("/x", handler)).Use(deny)
+	r.Group("/g", (&Group{}).Put("/x", handler))
+
+	cases := []struct {
+		method, path string
+		code         int
+		header       string
+	}{
+		{"GET", "/x", 200, "1"},
+		{"HEAD", "/x", 200, "1"},
+		{"POST", "/x", 401, ""},
+		{"PUT", "/x", 200, ""},
+		{"GET", "/g/x", 401, ""},
+		{"POST", "/g/x", 401, ""},
+		{"PUT", "/g/x", 200, ""},
+	}
+
+	for _, c := range cases {
+		rw := serve(r, c.method, c.path)
+		if rw.Code != c.code || rw.Header().Get("X-Get") != c.header {
+			t.Errorf("%s %s = %d, X-Get %q, want %d, %q", c.method, c.path, rw.Code, rw.Header().Get("X-Get"), c.code, c.header)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/log"
+)
+
+// Lister lists resources, GET /pattern
+type Lister interface {
+	List(*context.Context)
+}
+
+// Creator creates a resource, POST /pattern
+type Creator interface {
+	Create(*context.Context)
+}
+
+// Shower shows a resource, GET /pattern/:id
+type Shower interface {
+	Show(*context.Context)
+}
+
+// Updater replaces a resource, PUT /pattern/:id
+type Updater interface {
+	Update(*context.Context)
+}
+
+// Patcher updates part of a resource, PATCH /pattern/:id
+type Patcher interface {
+	Patch(*context.Context)
+}
+
+// Destroyer deletes a resource, DELETE /pattern/:id
+type Destroyer interface {
+	Destroy(*context.Context)
+}
+
+// Resource returns a group with routes of controller, controller implements any of
+// Lister, Creator, Shower, Updater, Patcher and Destroyer, and id of resource is
+// saved in context with "id". Like Sub, the group can be added to other groups.
+func (g *Group) Resource(pattern string, controller interface{}) *Group {
+
+	group := newGroup()
+	item := pattern + "/:id"
+	routes := make([]interface{}, 0, 6)
+
+	if c, ok := controller.(Lister); ok {
+		routes = append(routes, group.add("GET", pattern, c.List))
+	}
+
+	if c, ok := controller.(Creator); ok {
+		routes = append(routes, group.add("POST", pattern, c.Create))
+	}
+
+	if c, ok := controller.(Shower); ok {
+		routes = append(routes, group.add("GET", item, c.Show))
+	}
+
+	if c, ok := controller.(Updater); ok {
+		routes = append(routes, group.add("PUT", item, c.Update))
+	}
+
+	if c, ok := controller.(Patcher); ok {
+		routes = append(routes, group.add("PATCH", item, c.Patch))
+	}
+
+	if c, ok := controller.(Destroyer); ok {
+		routes = append(routes, group.add("DELETE", item, c.Destroy))
+	}
+
+	if len(routes) == 0 {
+		log.Warning("Resource %s has no action", pattern)
+	}
+
+	group.group("", routes...)
+	group.pattern = pattern
+
+	return group
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"strings"
+	"testing"
+)
+
+type users struct {
+	called *string
+}
+
+func (u users) List(ctx *context.Context)    { *u.called = "list" }
+func (u users) Create(ctx *context.Context)  { *u.called = "create" }
+func (u users) Show(ctx *context.Context)    { *u.called = "show " + ctx.Get("id") }
+func (u users) Destroy(ctx *context.Context) { *u.called = "destroy " + ctx.Get("id") }
+
+type comments struct {
+	users
+}
+
+func (c comments) Update(ctx *context.Context) {
+	*c.called = "update " + ctx.Get("uid") + " " + ctx.Get("id")
+}
+func (c comments) Patch(ctx *context.Context) {
+	*c.called = "patch " + ctx.Get("uid") + " " + ctx.Get("id")
+}
+
+func TestResource(t *testing.T) {
+
+	var called string
+
+	r := New()
+	r.Resource("/users", users{&called})
+	r.Resource("/users/:uid/comments", comments{users{&called}})
+	r.Group("/api", (&Group{}).Resource("/posts", users{&called}))
+
+	// Each action of controller becomes one route
+	got := []string{}
+	for _, route := range r.Routes() {
+		got = append(got, route.Method+" "+route.Pattern)
+	}
+
+	want := []string{
+		"GET /api/posts", "POST /api/posts", "DELETE /api/posts/:id", "GET /api/posts/:id",
+		"GET /users", "POST /users", "DELETE /users/:id", "GET /users/:id",
+		"GET /users/:uid/comments", "POST /users/:uid/comments", "DELETE /users/:uid/comments/:id",
+		"GET /users/:uid/comments/:id", "PATCH /users/:uid/comments/:id", "PUT /users/:uid/comments/:id",
+	}
+
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("routes of resources %q, want %q", got, want)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		called string
+	}{
+		{"GET", "/users", "list"},
+		{"POST", "/users", "create"},
+		{"GET", "/users/42", "show 42"},
+		{"DELETE", "/users/42", "destroy 42"},
+		{"PATCH", "/users/42/comments/7", "patch 42 7"},
+		{"GET", "/api/posts/1", "show 1"},
+	}
+
+	for _, c := range cases {
+		called = ""
+		serve(r, c.method, c.path)
+		if called != c.called {
+			t.Errorf("%s %s calls %q, want %q", c.method, c.path, called, c.called)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Errorf("Validate() = %s, want nil", err)
+	}
+}
+
+func TestResourceUse(t *testing.T) {
+
+	var called string
+	var trace []string
+
+	r := New()
+	r.Resource("/users", users{&called}).Use(func(ctx *context.Context, next func()) {
+		trace = append(trace, ctx.Method())
+		next()
+	})
+	r.Get("/other", func(ctx *context.Context) {})
+
+	serve(r, "GET", "/users")
+	serve(r, "DELETE", "/users/1")
+	serve(r, "GET", "/other")
+
+	if strings.Join(trace, " ") != "GET DELETE" {
+		t.Errorf("middleware of resource runs for %q, want GET DELETE", trace)
+	}
+}
@@ -1,66 +1,296 @@
 package router
 
 import (
-	"fmt"
+	"errors"
 	"github.com/raythorn/zebra/context"
 	"github.com/raythorn/zebra/oss"
+	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 type Route struct {
 	pattern string
-	regexp  *regexp.Regexp
+	name    string
+	tokens  []token
+	params  []paramSpec
 	actions map[string]Handler
 	group   *Group
 	oss     *oss.Oss
+
+	// handles are routes registered for methods in actions, routes with the same pattern
+	// are merged into one, and the route registered for a method keeps its middlewares,
+	// body limit, name and document
+	handles map[string]*Route
+
+	middlewares []Middleware
+
+	// maxBody replaces max size of request body of router if not zero, negative is no limit
+	maxBody int64
+
+	// version is the API version of route, nil if route has no version
+	version *version
+
+	// duplicates are methods registered more than once
+	duplicates []string
+
+	// doc is document of methods registered with route
+	doc *RouteDoc
 }
 
 func newRoute() *Route {
-	return &Route{"", nil, make(map[string]Handler), nil, nil}
+	return &Route{pattern: "", actions: make(map[string]Handler), handles: make(map[string]*Route)}
+}
+
+// Use adds middlewares wrapping handlers of methods registered with this route, other
+// methods of the same pattern are not affected, and they are wrapped by middlewares of
+// group
+func (r *Route) Use(middlewares ...Middleware) *Route {
+	r.middlewares = append(r.middlewares, middlewares...)
+	return r
+}
+
+// MaxBody sets max size of request body in bytes for methods registered with this route,
+// it replaces the one of router, and negative size means no limit
+func (r *Route) MaxBody(size int64) *Route {
+	r.maxBody = size
+	return r
 }
 
-func (r *Route) match(ctx *context.Context) bool {
+// Name sets name of route, which is used to build URL of this route. Name is of the
+// pattern, and Validate reports methods of a pattern with different names.
+func (r *Route) Name(name string) *Route {
+	r.name = name
+	return r
+}
+
+// URL builds path from pattern of route, pairs are names and values of params, and
+// extra pairs are added as query. Path of version is added for route of version.
+// Error returns if any param is missing or value doesn't match the regexp in pattern.
+func (r *Route) URL(pairs ...string) (string, error) {
+
+	if len(pairs)%2 != 0 {
+		return "", errors.New("Route: params MUST be pairs of name and value")
+	}
+
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+
+	used := make(map[string]bool, len(values))
+	path := ""
+	param := 0
+
+	for _, tok := range r.tokens {
+		switch tok.kind {
+		case tokenStatic:
+			path += tok.text
+			continue
+		case tokenParam:
+			value, ok := values[tok.name]
+			if !ok {
+				return "", errors.New("Route: param " + tok.name + " missing for " + r.pattern)
+			}
+
+			if check := r.params[param].check; check != nil && !check.MatchString(value) {
+				return "", errors.New("Route: param " + tok.name + " doesn't match " + tok.text)
+			}
+
+			used[tok.name] = true
+			path += url.PathEscape(value)
+		case tokenCatchAll:
+			value, ok := values[tok.name]
+			if !ok {
+				return "", errors.New("Route: param " + tok.name + " missing for " + r.pattern)
+			}
+
+			segments := strings.Split(value, "/")
+			for i := range segments {
+				segments[i] = url.PathEscape(segments[i])
+			}
+
+			used[tok.name] = true
+			path += strings.Join(segments, "/")
+		case tokenRegexp:
+			spec := r.params[param]
+			names := make([]string, 0, 1)
+			for _, name := range spec.regexp.SubexpNames() {
+				if name != "" {
+					names = append(names, name)
+				}
+			}
+
+			if len(names) != 1 {
+				return "", errors.New("Route: cannot build " + tok.text + " in " + r.pattern)
+			}
 
-	if _, ok := r.actions[ctx.Method()]; !ok {
-		if _, ok := r.actions["ANY"]; !ok {
-			return false
+			value, ok := values[names[0]]
+			if !ok {
+				return "", errors.New("Route: param " + names[0] + " missing for " + r.pattern)
+			}
+
+			if !spec.regexp.MatchString(value) {
+				return "", errors.New("Route: param " + names[0] + " doesn't match " + tok.text)
+			}
+
+			used[names[0]] = true
+			path += url.PathEscape(value)
+		}
+		param++
+	}
+
+	query := url.Values{}
+	for i := 0; i < len(pairs); i += 2 {
+		if !used[pairs[i]] {
+			query.Add(pairs[i], pairs[i+1])
 		}
 	}
 
-	if ctx.URL() == r.pattern {
-		return true
+	if r.version != nil {
+		path = r.version.path(path)
 	}
 
-	matches := r.regexp.FindStringSubmatch(ctx.URL())
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
+
+// compile splits pattern into tokens, and collects how wildcard values are saved
+func (r *Route) compile() {
+	r.tokens = tokenize(r.pattern)
+	r.params = r.params[:0]
 
-	if len(matches) > 0 && matches[0] == ctx.URL() {
-		for i, name := range r.regexp.SubexpNames() {
-			// log.Println(name)
-			if len(name) > 0 {
-				ctx.Set(name, matches[i])
+	for _, tok := range r.tokens {
+		switch tok.kind {
+		case tokenCatchAll:
+			r.params = append(r.params, paramSpec{name: tok.name})
+		case tokenParam:
+			spec := paramSpec{name: tok.name}
+			if tok.constraint != "" {
+				spec.check = regexp.MustCompile(`^(?:` + tok.constraint + `)$`)
 			}
+			r.params = append(r.params, spec)
+		case tokenRegexp:
+			r.params = append(r.params, paramSpec{regexp: regexp.MustCompile(`^(?:` + tok.text + `)$`)})
+		}
+	}
+}
+
+// method returns method of actions handling method, ANY handles all methods, and GET
+// handles HEAD if no HEAD handler set. Empty returns if method not allowed.
+func (r *Route) method(method string) string {
+	if _, ok := r.actions[method]; ok {
+		return method
+	}
+
+	if _, ok := r.actions["ANY"]; ok {
+		return "ANY"
+	}
+
+	if _, ok := r.actions["GET"]; ok && method == "HEAD" {
+		return "GET"
+	}
+
+	return ""
+}
+
+// allow adds methods allowed by route into methods, HEAD is allowed with GET, and
+// OPTIONS is always allowed
+func (r *Route) allow(methods map[string]bool) {
+	for method := range r.actions {
+		methods[method] = true
+	}
+
+	if methods["GET"] {
+		methods["HEAD"] = true
+	}
+
+	methods["OPTIONS"] = true
+}
+
+// merge adds handlers of route with the same pattern with the routes registered for
+// them, and records methods already handled as duplicates
+func (r *Route) merge(route *Route) {
+	for method, handler := range route.actions {
+		if _, ok := r.actions[method]; ok {
+			r.duplicates = append(r.duplicates, method)
+		}
+		r.actions[method] = handler
+		r.handles[method] = route.handles[method]
+	}
+}
+
+// named returns true if any method of route is registered with name
+func (r *Route) named(name string) bool {
+	for _, handle := range r.handles {
+		if handle.name == name {
+			return true
 		}
-		return true
 	}
 
 	return false
 }
 
-func (r *Route) regexpCompile() {
-	routeExp := regexp.MustCompile(`:[^/#?()\.\\]+`)
-	r.pattern = routeExp.ReplaceAllStringFunc(r.pattern, func(m string) string {
-		return fmt.Sprintf(`(?P<%s>[^/#?]+)`, m[1:])
-	})
+// names returns sorted names of methods of route, there is only one if route is named
+// without conflict
+func (r *Route) names() []string {
+	names := []string{}
+	seen := map[string]bool{"": true}
+	for _, handle := range r.handles {
+		if !seen[handle.name] {
+			seen[handle.name] = true
+			names = append(names, handle.name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// each calls f with route and routes registered for its methods, once for each of them
+func (r *Route) each(f func(*Route)) {
+	f(r)
+
+	seen := map[*Route]bool{r: true}
+	for _, handle := range r.handles {
+		if !seen[handle] {
+			seen[handle] = true
+			f(handle)
+		}
+	}
+}
 
-	pattern := r.pattern
-	if !strings.HasSuffix(pattern, `\/?`) {
-		pattern += `\/?`
+// catchAll returns name of catch-all param, empty if route has no catch-all
+func (r *Route) catchAll() string {
+	if n := len(r.tokens); n > 0 && r.tokens[n-1].kind == tokenCatchAll {
+		return r.tokens[n-1].name
 	}
 
-	r.regexp = regexp.MustCompile(pattern)
-	if strings.Contains(r.pattern, "(?P") {
-		r.pattern = pattern
+	return ""
+}
+
+// assign saves wildcard values matched in path into context
+func (r *Route) assign(ctx *context.Context, values []string) {
+	for i, spec := range r.params {
+		if i >= len(values) {
+			return
+		}
+
+		if spec.regexp == nil {
+			ctx.Set(spec.name, values[i])
+			continue
+		}
+
+		matches := spec.regexp.FindStringSubmatch(values[i])
+		for j, name := range spec.regexp.SubexpNames() {
+			if len(name) > 0 && j < len(matches) {
+				ctx.Set(name, matches[j])
+			}
+		}
 	}
 }
 
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"testing"
+)
+
+func TestRouteURL(t *testing.T) {
+
+	g := newGroup()
+	user := g.insert("GET", "/user/:id", nil)
+	page := g.insert("GET", `/page/(?P<num>\d+)`, nil)
+
+	cases := []struct {
+		route *Route
+		pairs []string
+		url   string
+	}{
+		{user, []string{"id", "42"}, "/user/42"},
+		{user, []string{"id", "a b/c"}, "/user/a%20b%2Fc"},
+		{user, []string{"id", "42", "page", "2", "sort", "name"}, "/user/42?page=2&sort=name"},
+		{page, []string{"num", "12"}, "/page/12"},
+	}
+
+	for _, c := range cases {
+		if url, err := c.route.URL(c.pairs...); err != nil || url != c.url {
+			t.Errorf("%s URL(%v) = %q, %v, want %q", c.route.pattern, c.pairs, url, err, c.url)
+		}
+	}
+
+	failures := []struct {
+		route *Route
+		pairs []string
+	}{
+		{user, nil},
+		{user, []string{"id"}},
+		{user, []string{"name", "ray"}},
+		{page, []string{"num", "abc"}},
+	}
+
+	for _, c := range failures {
+		if url, err := c.route.URL(c.pairs...); err == nil {
+			t.Errorf("%s URL(%v) = %q, want error", c.route.pattern, c.pairs, url)
+		}
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+
+	r := New()
+	r.Get("/user/:id", nil).Name("user.show")
+	r.Put("/user/:id", nil)
+	r.Group("/api", (&Group{}).Get("/post/:id/comments/:cid", nil).Name("comment"))
+
+	if url, err := r.URL("user.show", "id", "42"); err != nil || url != "/user/42" {
+		t.Errorf("URL(user.show) = %q, %v, want /user/42", url, err)
+	}
+
+	// Prefix of group is part of the URL
+	if url, err := r.URL("comment", "id", "1", "cid", "2"); err != nil || url != "/api/post/1/comments/2" {
+		t.Errorf("URL(comment) = %q, %v, want /api/post/1/comments/2", url, err)
+	}
+
+	if _, err := r.URL("unknown"); err == nil {
+		t.Error("URL(unknown) = nil error, want not found")
+	}
+
+	// Name of another method doesn't replace the one of pattern
+	r.Put("/user/:id", nil).Name("user.update")
+	if url, err := r.URL("user.show", "id", "42"); err != nil || url != "/user/42" {
+		t.Errorf("URL(user.show) after naming PUT = %q, %v, want /user/42", url, err)
+	}
+
+	r.Get("/profile/:id", nil).Name("user.show")
+	if _, err := r.URL("user.show", "id", "42"); err == nil {
+		t.Error("URL of duplicated name = nil error, want not unique")
+	}
+}
@@ -1,15 +1,23 @@
 package router
 
 import (
+	"errors"
 	"github.com/raythorn/zebra/context"
 	"github.com/raythorn/zebra/log"
 	"github.com/raythorn/zebra/oss"
 	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
 )
 
 type Handler func(*context.Context)
 type Midware func(*context.Context) bool
 
+// RecoveryHandler handles panic recovered from handler, err is the value of panic
+type RecoveryHandler func(ctx *context.Context, err interface{})
+
 type Router interface {
 
 	// Add midware to router, these handler will called before every request handler.
@@ -18,36 +26,66 @@ type Router interface {
 	// all following midwares and handlers will not be executed
 	Use(Midware)
 
+	// Wrap adds middlewares wrapping handlers of all routes, they are called after route
+	// matched, and wrap middlewares of groups and routes
+	Wrap(...Middleware)
+
 	// Group add a groupped router, all router has a same prefix, and should use GGet/GPut/GPatch...
 	// for add groupped router, and GSub can add a sub-group for current group
 	Group(string, ...interface{}) *Group
 
-	// Oss add a object storage sevice, which can download and upload objects(file/image...)
-	Oss(string, string, oss.Archive)
+	// Version adds routes and groups of API version, version is selected as Versioning
+	// configured, and a route not in the version falls back to older versions, which
+	// are added before it. Requests to hosts added by Host are not versioned.
+	Version(string, ...interface{}) *Group
+
+	// Versioning configures how version of API is selected
+	Versioning(Versioning)
+
+	// Deprecate marks version deprecated, responses of it have Deprecation header, and
+	// Sunset header if sunset is not zero
+	Deprecate(string, time.Time)
+
+	// Resource adds RESTful routes of controller, see Group.Resource, the returned group
+	// can be used to add middlewares
+	Resource(string, interface{}) *Group
+
+	// Host adds routes and groups served only for requests to host, which can have params,
+	// such as ":tenant.example.com", values of params are saved in context like path
+	// params. Requests to hosts not added are served by routes without host.
+	Host(string, ...interface{}) *Group
+
+	// Mount serves all requests under prefix with a net/http handler, prefix is stripped
+	// from path of request, such as Mount("/debug/pprof", mux)
+	Mount(string, http.Handler)
+
+	// Oss add a object storage sevice, which can download and upload objects(file/image...),
+	// value of catch-all in pattern, such as "/static/*file", is saved with oss.OssFileKey
+	Oss(string, string, oss.Archive) *Route
 
 	// Get adds a route for a HTTP GET request to the specified matching pattern.
-	Get(string, Handler)
+	Get(string, Handler) *Route
 
 	// Patch adds a route for a HTTP PATCH request to the specified matching pattern.
-	Patch(string, Handler)
+	Patch(string, Handler) *Route
 
 	// Put adds a route for a HTTP PUT request to the specified matching pattern.
-	Put(string, Handler)
+	Put(string, Handler) *Route
 
 	// Post adds a route for a HTTP POST request to the specified matching pattern.
-	Post(string, Handler)
+	Post(string, Handler) *Route
 
 	// Delete adds a route for a HTTP DELETE request to the specified matching pattern.
-	Delete(string, Handler)
+	Delete(string, Handler) *Route
 
 	// Head adds a route for a HTTP HEAD request to the specified matching pattern.
-	Head(string, Handler)
+	Head(string, Handler) *Route
 
 	// Options adds a route for a HTTP OPTIONS request to the specified matching pattern.
-	Options(string, Handler)
+	Options(string, Handler) *Route
 
 	// Any adds a route for any HTTP method request to the specified matching pattern.
-	Any(string, Handler)
+	Any(string, Handler) *Route
 
 	// NotFound sets the handlers that are called when a no route matches a request. Throws a basic 404 by default.
 	NotFound(Handler)
@@ -55,6 +93,26 @@ type Router interface {
 	// NotAllowed sets the handler that are called when a not allowed http method request
 	NotAllowed(Handler)
 
+	// Recovery sets the handler that are called when handler panics, stack is logged and
+	// a basic 500 is thrown by default. Panic of ctx.Intercept is not handled.
+	Recovery(RecoveryHandler)
+
+	// MaxBody sets max size of request body in bytes, a basic 413 is thrown if body is
+	// larger, route can change it with Route.MaxBody. Form body and body read by midwares
+	// are limited before route is found. No limit by default.
+	MaxBody(int64)
+
+	// URL builds path of the route with name, pairs are names and values of params in
+	// pattern, such as URL("user.show", "id", "42"), and extra pairs are added as query
+	URL(string, ...string) (string, error)
+
+	// Routes returns all routes for each method, sorted by host, pattern and method
+	Routes() []RouteInfo
+
+	// Validate returns error if any route conflicts with others, registered more than once
+	// or shadowed by group route
+	Validate() error
+
 	// Handle is the entry point for routing.
 	Handle(http.ResponseWriter, *http.Request)
 }
@@ -62,9 +120,18 @@ type Router interface {
 type router struct {
 	route      *Group
 	group      *Group
+	hosts      *Group
+	hostTables map[string]*Group
+	defaults   []*Group
+	versions   []*version
+	versioning Versioning
 	midwares   []Midware
+	wraps      []Middleware
 	notfound   Handler
 	notallowed Handler
+	recovery   RecoveryHandler
+	maxBody    int64
+	urlfor     context.URLBuilder
 }
 
 func New() Router {
@@ -72,12 +139,17 @@ func New() Router {
 	r := &router{
 		route:      newGroup(),
 		group:      newGroup(),
+		hosts:      newGroup(),
+		hostTables: make(map[string]*Group),
 		midwares:   make([]Midware, 0),
 		notfound:   nil,
 		notallowed: nil,
 	}
 
 	r.route.pattern = "/"
+	r.defaults = []*Group{r.group, r.route}
+	r.versioning.Header = "X-API-Version"
+	r.urlfor = r.URL
 
 	return r
 }
@@ -86,52 +158,105 @@ func (r *router) Use(midware Midware) {
 	r.midwares = append(r.midwares, midware)
 }
 
+func (r *router) Wrap(middlewares ...Middleware) {
+	r.wraps = append(r.wraps, middlewares...)
+}
+
 func (r *router) Group(prefix string, args ...interface{}) *Group {
 
 	path := cleanPath(prefix)
 
-	return r.group.group(path, args...)
+	g := newGroup()
+	g.group(path, args...)
+	g.pattern = path
+	r.group.mount(g)
+
+	return g
 }
 
-func (r *router) Oss(pattern, root string, archive oss.Archive) {
+func (r *router) Resource(pattern string, controller interface{}) *Group {
+
+	g := r.group.Resource(cleanPath(pattern), controller)
+	r.group.mount(g)
+
+	return g
+}
+
+func (r *router) Host(pattern string, args ...interface{}) *Group {
+
+	pattern = strings.ToLower(pattern)
+
+	table, ok := r.hostTables[pattern]
+	if !ok {
+		route := newRoute()
+		route.pattern = pattern
+		route.compile()
+
+		r.hosts.routes[pattern] = route
+		r.hosts.invalidate()
+
+		table = newGroup()
+		r.hostTables[pattern] = table
+	}
+
+	g := newGroup()
+	g.group("/", args...)
+	g.pattern = "/"
+	table.mount(g)
+
+	return g
+}
+
+func (r *router) Mount(prefix string, handler http.Handler) {
+
+	prefix = cleanPath(prefix)
+	h := mount(strings.TrimSuffix(prefix, "/"), handler)
+
+	r.route.insert("ANY", prefix, h)
+	r.route.insert("ANY", strings.TrimSuffix(prefix, "/")+"/*path", h)
+}
 
-	route := r.route.insert("GET", pattern, oss.ServeContent)
+func (r *router) Oss(pattern, root string, archive oss.Archive) *Route {
+
+	route := r.route.add("GET", pattern, oss.ServeContent)
 	route.actions["HEAD"] = oss.ServeContent
 	route.actions["POST"] = oss.DepositContent
+	route.handles["HEAD"] = route
+	route.handles["POST"] = route
 	route.oss = oss.New(root, archive)
+	return r.route.put(route)
 }
 
-func (r *router) Get(pattern string, handler Handler) {
-
-	r.route.insert("GET", pattern, handler)
+func (r *router) Get(pattern string, handler Handler) *Route {
+	return r.route.insert("GET", pattern, handler)
 }
 
-func (r *router) Patch(pattern string, handler Handler) {
-	r.route.insert("PATCH", pattern, handler)
+func (r *router) Patch(pattern string, handler Handler) *Route {
+	return r.route.insert("PATCH", pattern, handler)
 }
 
-func (r *router) Put(pattern string, handler Handler) {
-	r.route.insert("PUT", pattern, handler)
+func (r *router) Put(pattern string, handler Handler) *Route {
+	return r.route.insert("PUT", pattern, handler)
 }
 
-func (r *router) Post(pattern string, handler Handler) {
-	r.route.insert("POST", pattern, handler)
+func (r *router) Post(pattern string, handler Handler) *Route {
+	return r.route.insert("POST", pattern, handler)
 }
 
-func (r *router) Delete(pattern string, handler Handler) {
-	r.route.insert("DELETE", pattern, handler)
+func (r *router) Delete(pattern string, handler Handler) *Route {
+	return r.route.insert("DELETE", pattern, handler)
 }
 
-func (r *router) Head(pattern string, handler Handler) {
-	r.route.insert("HEAD", pattern, handler)
+func (r *router) Head(pattern string, handler Handler) *Route {
+	return r.route.insert("HEAD", pattern, handler)
 }
 
-func (r *router) Options(pattern string, handler Handler) {
-	r.route.insert("OPTIONS", pattern, handler)
+func (r *router) Options(pattern string, handler Handler) *Route {
+	return r.route.insert("OPTIONS", pattern, handler)
 }
 
-func (r *router) Any(pattern string, handler Handler) {
-	r.route.insert("ANY", pattern, handler)
+func (r *router) Any(pattern string, handler Handler) *Route {
+	return r.route.insert("ANY", pattern, handler)
 }
 
 func (r *router) NotFound(handler Handler) {
@@ -142,15 +267,91 @@ func (r *router) NotAllowed(handler Handler) {
 	r.notallowed = handler
 }
 
+func (r *router) Recovery(handler RecoveryHandler) {
+	r.recovery = handler
+}
+
+func (r *router) MaxBody(size int64) {
+	r.maxBody = size
+}
+
+func (r *router) URL(name string, pairs ...string) (string, error) {
+	return r.url(nil, name, pairs...)
+}
+
+// url builds URL of named route, a name is unique in routes without version and in each
+// version. Routes are searched from version from, or default version if nil, to the oldest
+// one as requests are served, then routes without version, and newer versions at last.
+func (r *router) url(from *version, name string, pairs ...string) (string, error) {
+
+	index := len(r.versions) - 1
+	if from != nil {
+		index = r.lookupVersion(from.name)
+	} else if i := r.lookupVersion(r.versioning.Default); i >= 0 {
+		index = i
+	}
+
+	scopes := make([][]*Group, 0, len(r.versions)+1)
+	for i := index; i >= 0; i-- {
+		scopes = append(scopes, []*Group{r.versions[i].table})
+	}
+
+	unversioned := []*Group{}
+	for _, t := range r.tables() {
+		if t.version == "" {
+			unversioned = append(unversioned, t.group)
+		}
+	}
+	scopes = append(scopes, unversioned)
+
+	for i := len(r.versions) - 1; i > index; i-- {
+		scopes = append(scopes, []*Group{r.versions[i].table})
+	}
+
+	for _, scope := range scopes {
+		var found *Route
+		for _, g := range scope {
+			for _, route := range g.routes {
+				if !route.named(name) {
+					continue
+				}
+
+				if found != nil && found != route {
+					return "", errors.New("Router: route name " + name + " is not unique")
+				}
+				found = route
+			}
+		}
+
+		if found != nil {
+			return found.URL(pairs...)
+		}
+	}
+
+	return "", errors.New("Router: route " + name + " not found")
+}
+
 func (r *router) Handle(rw http.ResponseWriter, req *http.Request) {
 
-	r.recovery()
+	// Body of HEAD response is discarded, as GET handler serves HEAD request
+	if req.Method == "HEAD" {
+		rw = &headWriter{rw}
+	}
+
+	// Body of form and body read by midwares are limited by router, route changes the limit
+	// before its handler
+	if r.maxBody > 0 && req.Body != nil {
+		req.Body = context.LimitReader(req.Body, r.maxBody)
+	}
 
 	ctx := context.New()
-	ctx.Reset(rw, req)
+	if err := ctx.Reset(rw, req); err == context.ErrBodyTooLarge {
+		http.Error(rw, "413 request entity too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	ctx.SetURLBuilder(r.urlfor)
 
-	// log.Printf("URI: %s", ctx.URI())
-	// log.Printf("PATH: %s", ctx.URL())
+	defer r.rescue(ctx)
 
 	//Call all midware first
 	if len(r.midwares) > 0 {
@@ -161,71 +362,160 @@ func (r *router) Handle(rw http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	//Search Group
-	route := r.group.match(ctx)
-	if route != nil {
-		var handler Handler = nil
-		var ok bool = false
-
-		// Check route exist or not, if not eixst return with notfound handler
-		if handler, ok = route.actions[ctx.Method()]; !ok {
-			if r.notfound != nil {
-				r.notfound(ctx)
-			} else {
-				http.NotFound(rw, req)
+	//Search routes of host only if host matches, otherwise routes of version first, then
+	//group routes, then routes, a route matches path but not method is kept for computing
+	//Allow header
+	methods := map[string]bool{}
+	tables, host := r.tree(ctx)
+
+	if !host {
+		if v, tables, path := r.version(ctx); v != nil {
+			if route, handler, values := r.find(ctx, tables, path, methods); route != nil {
+				v.apply(ctx)
+				route.assign(ctx, values)
+				r.serve(ctx, route, handler)
+				return
 			}
+		}
+	}
+
+	if route, handler, values := r.find(ctx, tables, ctx.URL(), methods); route != nil {
+		route.assign(ctx, values)
+		r.serve(ctx, route, handler)
+		return
+	}
 
-			return
+	if len(methods) > 0 {
+		allow := make([]string, 0, len(methods))
+		for method := range methods {
+			allow = append(allow, method)
+		}
+		sort.Strings(allow)
+		ctx.Header("Allow", strings.Join(allow, ", "))
+
+		if ctx.Method() == "OPTIONS" {
+			ctx.WriteHeader(http.StatusNoContent)
+		} else if r.notallowed != nil {
+			r.notallowed(ctx)
+		} else {
+			http.Error(rw, "405 method not allowed", http.StatusMethodNotAllowed)
 		}
 
-		if route.group != nil && len(route.group.before) > 0 {
-			for _, midware := range route.group.before {
-				if !midware(ctx) {
-					return
-				}
-			}
+		return
+	}
+
+	//Not found
+	if r.notfound != nil {
+		r.notfound(ctx)
+	} else {
+		http.NotFound(rw, req)
+	}
+}
+
+// find returns route registered for method of the first route matches path and method in
+// tables with its handler and wildcard values, and adds methods of routes only match path
+// into methods
+func (r *router) find(ctx *context.Context, tables []*Group, path string, methods map[string]bool) (*Route, Handler, []string) {
+
+	for _, g := range tables {
+		route, values := g.match(path)
+		if route == nil {
+			continue
 		}
 
-		handler(ctx)
+		if method := route.method(ctx.Method()); method != "" {
+			return route.handles[method], route.actions[method], values
+		}
 
-		if route.group != nil && len(route.group.after) > 0 {
-			for _, midware := range route.group.after {
-				if !midware(ctx) {
-					return
-				}
-			}
+		route.allow(methods)
+	}
+
+	return nil, nil, nil
+}
+
+// tree returns route tables for host of request and true, or routes without host and
+// false if no host matches
+func (r *router) tree(ctx *context.Context) ([]*Group, bool) {
+
+	if len(r.hostTables) > 0 {
+		if route, values := r.hosts.lookup().find(strings.ToLower(ctx.Host()), nil); route != nil {
+			route.assign(ctx, values)
+			return []*Group{r.hostTables[route.pattern]}, true
 		}
+	}
+
+	return r.defaults, false
+}
+
+// serve calls handler of route wrapped by middlewares of router, groups and route
+func (r *router) serve(ctx *context.Context, route *Route, handler Handler) {
+
+	limit := r.maxBody
+	if route.maxBody != 0 {
+		limit = route.maxBody
+	}
 
+	if limit != 0 && !ctx.LimitBody(limit) {
+		http.Error(ctx.ResponseWriter(), "413 request entity too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	// Search route
-	route = r.route.match(ctx)
-	if route != nil {
+	if route.oss != nil {
+		if name := route.catchAll(); name != "" {
+			ctx.Set(oss.OssFileKey, ctx.Get(name))
+		}
+		ctx.Set(oss.OssPathKey, route.oss.Archive().Path(route.oss, ctx))
+	}
 
-		if h, ok := route.actions[ctx.Method()]; ok {
-			if route.oss != nil {
-				ctx.Set(oss.OssPathKey, route.oss.Archive().Path(route.oss, ctx))
-			}
+	middlewares := make([]Middleware, 0, len(r.wraps)+len(route.middlewares))
+	middlewares = append(middlewares, r.wraps...)
+	middlewares = route.group.chain(middlewares)
+	middlewares = append(middlewares, route.middlewares...)
 
-			h(ctx)
-		}
+	chain(ctx, middlewares, func() {
+		handler(ctx)
+	})
+}
 
+// rescue recovers panic of handler, ctx.Intercept aborts request silently, and other
+// panic is logged with stack and handled by recovery handler
+func (r *router) rescue(ctx *context.Context) {
+
+	err := recover()
+	if err == nil {
 		return
 	}
 
-	//Not found
-	if r.notfound != nil {
-		r.notfound(ctx)
+	if abort, ok := err.(*context.Aborted); ok {
+		log.Debug("%s %s aborted: %s", ctx.Method(), ctx.URL(), abort.Reason)
+		return
+	}
+
+	// Let net/http abort response silently
+	if err == http.ErrAbortHandler {
+		panic(err)
+	}
+
+	log.Error("%s %s panic: %v\n%s", ctx.Method(), ctx.URL(), err, debug.Stack())
+
+	if r.recovery != nil {
+		r.recovery(ctx, err)
 	} else {
-		http.NotFound(rw, req)
+		http.Error(ctx.ResponseWriter(), "500 internal server error", http.StatusInternalServerError)
 	}
 }
 
-func (r *router) recovery() {
-	defer func() {
-		if err := recover(); err != nil {
-			log.Error("%s\n", err)
-		}
-	}()
+// headWriter discards response body of HEAD request
+type headWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *headWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
@@ -0,0 +1,225 @@
+package router
+
+import (
+	"fmt"
+	"github.com/raythorn/zebra/context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+
+	r := New()
+	r.Get("/user/:id", func(ctx *context.Context) {
+		ctx.Header("X-User", ctx.Get("id"))
+		ctx.WriteString("user")
+	})
+	r.Put("/user/:id", func(ctx *context.Context) {})
+	r.Any("/any", func(ctx *context.Context) { ctx.WriteString(ctx.Method()) })
+	r.Group("/api", (&Group{}).Get("/status", func(ctx *context.Context) {}))
+
+	rw := serve(r, "DELETE", "/user/1")
+	if rw.Code != http.StatusMethodNotAllowed || rw.Header().Get("Allow") != "GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("DELETE /user/1 = %d, Allow %q", rw.Code, rw.Header().Get("Allow"))
+	}
+
+	// Methods of group routes are allowed too
+	if rw := serve(r, "POST", "/api/status"); rw.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("POST /api/status Allow %q, want GET, HEAD, OPTIONS", rw.Header().Get("Allow"))
+	}
+
+	rw = serve(r, "OPTIONS", "/user/1")
+	if rw.Code != http.StatusNoContent || rw.Header().Get("Allow") != "GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("OPTIONS /user/1 = %d, Allow %q", rw.Code, rw.Header().Get("Allow"))
+	}
+
+	// HEAD is served by GET handler, headers are kept and body is discarded
+	rw = serve(r, "HEAD", "/user/1")
+	if rw.Code != http.StatusOK || rw.Header().Get("X-User") != "1" || rw.Body.Len() != 0 {
+		t.Errorf("HEAD /user/1 = %d, X-User %q, body %q", rw.Code, rw.Header().Get("X-User"), rw.Body)
+	}
+
+	if rw := serve(r, "DELETE", "/any"); rw.Body.String() != "DELETE" {
+		t.Errorf("DELETE /any = %q, want served by Any", rw.Body)
+	}
+
+	if rw := serve(r, "GET", "/none"); rw.Code != http.StatusNotFound || rw.Header().Get("Allow") != "" {
+		t.Errorf("GET /none = %d, Allow %q, want 404", rw.Code, rw.Header().Get("Allow"))
+	}
+
+	var allow string
+	r.NotAllowed(func(ctx *context.Context) {
+		allow = ctx.ResponseWriter().Header().Get("Allow")
+		ctx.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	if serve(r, "POST", "/user/1"); allow != "GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("NotAllowed handler sees Allow %q", allow)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+
+	r := New()
+	r.Get("/panic", func(ctx *context.Context) { panic("boom") })
+	r.Get("/intercept", func(ctx *context.Context) {
+		ctx.Intercept([]byte("denied"), http.StatusForbidden, "no permission")
+		ctx.WriteString("unreachable")
+	})
+	r.Get("/abort", func(ctx *context.Context) { panic(http.ErrAbortHandler) })
+
+	if rw := serve(r, "GET", "/panic"); rw.Code != http.StatusInternalServerError {
+		t.Errorf("GET /panic = %d, want 500 by default", rw.Code)
+	}
+
+	var recovered interface{}
+	r.Recovery(func(ctx *context.Context, err interface{}) {
+		recovered = err
+		ctx.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	if rw := serve(r, "GET", "/panic"); rw.Code != http.StatusServiceUnavailable || recovered != "boom" {
+		t.Errorf("GET /panic = %d, recovered %v, want 503 boom", rw.Code, recovered)
+	}
+
+	// Intercept finishes request without recovery handler
+	recovered = nil
+	if rw := serve(r, "GET", "/intercept"); rw.Code != http.StatusForbidden || rw.Body.String() != "denied" || recovered != nil {
+		t.Errorf("GET /intercept = %d %q, recovered %v", rw.Code, rw.Body, recovered)
+	}
+
+	// net/http aborts response by ErrAbortHandler, so it's panicked again
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("GET /abort panics %v, want http.ErrAbortHandler", err)
+		}
+	}()
+	serve(r, "GET", "/abort")
+}
+
+func TestMaxBody(t *testing.T) {
+
+	r := New()
+	r.MaxBody(8)
+	r.Post("/body", func(ctx *context.Context) { ctx.Write(ctx.Body()) })
+	r.Post("/upload", func(ctx *context.Context) {
+		n, _ := io.Copy(ioutil.Discard, ctx.BodyReader())
+		ctx.WriteString(fmt.Sprint(n))
+	}).MaxBody(-1)
+	r.Post("/stream", func(ctx *context.Context) {
+		if _, err := io.Copy(ioutil.Discard, ctx.BodyReader()); err != nil {
+			ctx.WriteString(err.Error())
+		}
+	}).MaxBody(16)
+
+	cases := []struct {
+		path    string
+		body    string
+		chunked bool
+		code    int
+		want    string
+	}{
+		{"/body", "data", false, 200, "data"},
+		{"/body", "0123456789", false, 413, "413 request entity too large\n"},
+		{"/body", "0123456789", true, 413, "413 request entity too large\n"},
+		{"/upload", strings.Repeat("a", 1024), true, 200, "1024"},
+		{"/stream", "0123456789", true, 200, ""},
+		{"/stream", strings.Repeat("a", 20), true, 200, context.ErrBodyTooLarge.Error()},
+	}
+
+	for _, c := range cases {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", c.path, strings.NewReader(c.body))
+		if c.chunked {
+			req.ContentLength = -1
+			req.Body = ioutil.NopCloser(strings.NewReader(c.body))
+		}
+		r.Handle(rw, req)
+
+		if rw.Code != c.code || rw.Body.String() != c.want {
+			t.Errorf("%s %d bytes = %d %q, want %d %q", c.path, len(c.body), rw.Code, rw.Body, c.code, c.want)
+		}
+	}
+}
+
+func TestMaxBodyBeforeRoute(t *testing.T) {
+
+	r := New()
+	r.MaxBody(8)
+	r.Use(func(ctx *context.Context) bool {
+		if ctx.Get("X-Read") != "" {
+			ctx.Body()
+		}
+		return true
+	})
+	r.Post("/form", func(ctx *context.Context) { ctx.WriteString(ctx.Get("name")) })
+	r.Post("/upload", func(ctx *context.Context) {
+		n, _ := io.Copy(ioutil.Discard, ctx.BodyReader())
+		ctx.WriteString(fmt.Sprint(n))
+	}).MaxBody(-1)
+
+	cases := []struct {
+		path, body, contentType string
+		read                    bool
+		code                    int
+		want                    string
+	}{
+		{"/form", "name=ray", "application/x-www-form-urlencoded", false, 200, "ray"},
+		{"/upload", "name=" + strings.Repeat("a", 20), "application/x-www-form-urlencoded", false, 413, "413 request entity too large\n"},
+		{"/upload", strings.Repeat("a", 1024), "application/octet-stream", false, 200, "1024"},
+		{"/upload", strings.Repeat("a", 1024), "application/octet-stream", true, 413, "413 request entity too large\n"},
+		{"/upload", "data", "application/octet-stream", true, 200, "4"},
+	}
+
+	for _, c := range cases {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", c.path, strings.NewReader(c.body))
+		req.Header.Set("Content-Type", c.contentType)
+		if c.read {
+			req.Header.Set("X-Read", "1")
+		}
+		r.Handle(rw, req)
+
+		if rw.Code != c.code || rw.Body.String() != c.want {
+			t.Errorf("%s %s %d bytes, read by midware %v = %d %q, want %d %q", c.path, c.contentType, len(c.body), c.read, rw.Code, rw.Body, c.code, c.want)
+		}
+	}
+}
+
+func TestMaxBodyOfMethod(t *testing.T) {
+
+	handler := func(ctx *context.Context) { ctx.Write(ctx.Body()) }
+
+	r := New()
+	r.MaxBody(8)
+	r.Get("/x", handler)
+	r.Post("/x", handler).MaxBody(1)
+	r.Put("/x", handler).MaxBody(-1)
+	r.Patch("/x", handler)
+
+	cases := []struct {
+		method, body string
+		code         int
+	}{
+		{"GET", "data", 200},
+		{"GET", "0123456789", 413},
+		{"POST", "d", 200},
+		{"POST", "data", 413},
+		{"PUT", strings.Repeat("a", 1024), 200},
+		{"PATCH", "data", 200},
+		{"PATCH", "0123456789", 413},
+	}
+
+	for _, c := range cases {
+		rw := httptest.NewRecorder()
+		r.Handle(rw, httptest.NewRequest(c.method, "/x", strings.NewReader(c.body)))
+
+		if rw.Code != c.code {
+			t.Errorf("%s /x %d bytes = %d, want %d", c.method, len(c.body), rw.Code, c.code)
+		}
+	}
+}
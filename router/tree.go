@@ -0,0 +1,323 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of pattern token
+const (
+	tokenStatic = iota
+	tokenParam
+	tokenRegexp
+	tokenCatchAll
+)
+
+// Constraints of param, other constraint is used as regexp directly
+var constraints = map[string]string{
+	"int":  `-?[0-9]+`,
+	"uint": `[0-9]+`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// token is a piece of route pattern, static text, named param ":name" with an
+// optional constraint ":name<int>", named regexp "(?P<name>exp)", or catch-all
+// "*name" which matches the rest of path
+type token struct {
+	kind       int
+	text       string
+	name       string
+	rule       string
+	constraint string
+}
+
+// paramSpec describes how a matched wildcard value is saved into context, param
+// value is saved with its name, and regexp value is matched again to extract its
+// named groups. check is the constraint of param.
+type paramSpec struct {
+	name   string
+	regexp *regexp.Regexp
+	check  *regexp.Regexp
+}
+
+// constraint converts constraint of param into regexp, "int", "uint", "uuid" and
+// "enum(a|b)" are predefined, others are regexps
+func constraint(text string) string {
+	if exp, ok := constraints[text]; ok {
+		return exp
+	}
+
+	if strings.HasPrefix(text, "enum(") && strings.HasSuffix(text, ")") {
+		values := strings.Split(text[len("enum("):len(text)-1], "|")
+		for i := range values {
+			values[i] = regexp.QuoteMeta(values[i])
+		}
+		return strings.Join(values, "|")
+	}
+
+	return text
+}
+
+// node is a node of compressed radix tree. Static children are indexed by their
+// first byte, and wildcard children are tried after static children, regexps
+// before params, in the order they added, and catch-all is the last one.
+// Matching backtracks, so a request falls back to a less specific route if the
+// more specific branch has no route.
+type node struct {
+	prefix   string
+	indices  string
+	children []*node
+	regexps  []*node
+	params   []*node
+	catchall *node
+
+	// key identifies wildcard node, and regexp matches value of it
+	key    string
+	regexp *regexp.Regexp
+
+	route *Route
+}
+
+// tokenize splits a pattern into tokens, a param name ends with any of "/#?().\<"
+// and is followed by an optional constraint in "<>", a regexp ends with "/" outside
+// of parentheses, and a catch-all "*name" follows "/" and ends the pattern
+func tokenize(pattern string) []token {
+
+	tokens := make([]token, 0, 4)
+	start := 0
+
+	flush := func(end int) {
+		if end > start {
+			tokens = append(tokens, token{kind: tokenStatic, text: pattern[start:end]})
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ':':
+			end := i + 1
+			for end < len(pattern) && !strings.ContainsRune(`/#?().\<`, rune(pattern[end])) {
+				end++
+			}
+
+			if end == i+1 {
+				i++
+				continue
+			}
+
+			tok := token{kind: tokenParam, name: pattern[i+1 : end]}
+			if end < len(pattern) && pattern[end] == '<' {
+				depth := 0
+				stop := end
+				for ; stop < len(pattern); stop++ {
+					if pattern[stop] == '<' {
+						depth++
+					} else if pattern[stop] == '>' {
+						if depth--; depth == 0 {
+							break
+						}
+					}
+				}
+
+				if stop < len(pattern) {
+					tok.rule = pattern[end+1 : stop]
+					tok.constraint = constraint(tok.rule)
+					end = stop + 1
+				}
+			}
+
+			flush(i)
+			tok.text = pattern[i:end]
+			tokens = append(tokens, tok)
+			i, start = end, end
+		case '*':
+			if i == 0 || pattern[i-1] != '/' || i+1 == len(pattern) {
+				i++
+				continue
+			}
+
+			flush(i)
+			tokens = append(tokens, token{kind: tokenCatchAll, text: pattern[i:], name: pattern[i+1:]})
+			return tokens
+		case '(':
+			depth := 0
+			end := i
+			for ; end < len(pattern); end++ {
+				if pattern[end] == '\\' {
+					end++
+				} else if pattern[end] == '(' {
+					depth++
+				} else if pattern[end] == ')' {
+					depth--
+				} else if pattern[end] == '/' && depth <= 0 {
+					break
+				}
+			}
+
+			if end > len(pattern) {
+				end = len(pattern)
+			}
+
+			flush(i)
+			tokens = append(tokens, token{kind: tokenRegexp, text: pattern[i:end]})
+			i, start = end, end
+		default:
+			i++
+		}
+	}
+
+	flush(len(pattern))
+	return tokens
+}
+
+// insert adds route with tokens to tree, and returns the route already in the
+// same place if exist, otherwise returns route itself
+func (n *node) insert(tokens []token, route *Route) *Route {
+
+	for _, tok := range tokens {
+		switch tok.kind {
+		case tokenStatic:
+			n = n.static(tok.text)
+		case tokenParam:
+			var exp *regexp.Regexp
+			if tok.constraint != "" {
+				exp = regexp.MustCompile(`^(?:` + tok.constraint + `)$`)
+			}
+			n = n.wildcard(&n.params, tok.constraint, exp)
+		case tokenRegexp:
+			n = n.wildcard(&n.regexps, tok.text, regexp.MustCompile(`^(?:`+tok.text+`)`))
+		case tokenCatchAll:
+			if n.catchall == nil {
+				n.catchall = &node{}
+			}
+			n = n.catchall
+		}
+	}
+
+	if n.route != nil {
+		return n.route
+	}
+
+	n.route = route
+	return route
+}
+
+// static walks down along text, and splits nodes if text diverges in the middle
+// of a node prefix
+func (n *node) static(text string) *node {
+
+	for text != "" {
+		i := strings.IndexByte(n.indices, text[0])
+		if i < 0 {
+			child := &node{prefix: text}
+			n.indices += text[:1]
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+
+		l := 0
+		for l < len(text) && l < len(child.prefix) && text[l] == child.prefix[l] {
+			l++
+		}
+
+		if l < len(child.prefix) {
+			split := &node{
+				prefix:   child.prefix[:l],
+				indices:  child.prefix[l : l+1],
+				children: []*node{child},
+			}
+			child.prefix = child.prefix[l:]
+			n.children[i] = split
+			child = split
+		}
+
+		text = text[l:]
+		n = child
+	}
+
+	return n
+}
+
+// wildcard returns child in list with key, and adds one if not exist, param with
+// constraint is added before the one without constraint, so it's tried first
+func (n *node) wildcard(list *[]*node, key string, exp *regexp.Regexp) *node {
+	for _, child := range *list {
+		if child.key == key {
+			return child
+		}
+	}
+
+	child := &node{key: key, regexp: exp}
+	*list = append(*list, child)
+
+	if key != "" {
+		for i := len(*list) - 1; i > 0 && (*list)[i-1].key == ""; i-- {
+			(*list)[i-1], (*list)[i] = (*list)[i], (*list)[i-1]
+		}
+	}
+
+	return child
+}
+
+// find searches route matches path, path is the rest after current node, and
+// values collects wildcard values along the way. Nothing is allocated for static
+// routes.
+func (n *node) find(path string, values []string) (*Route, []string) {
+
+	if path == "" && n.route != nil {
+		return n.route, values
+	}
+
+	if path != "" {
+		if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+			child := n.children[i]
+			if len(path) >= len(child.prefix) && path[:len(child.prefix)] == child.prefix {
+				if route, vals := child.find(path[len(child.prefix):], values); route != nil {
+					return route, vals
+				}
+			}
+		}
+	}
+
+	for _, child := range n.regexps {
+		if loc := child.regexp.FindStringIndex(path); loc != nil && loc[1] > 0 {
+			if route, vals := child.find(path[loc[1]:], append(values, path[:loc[1]])); route != nil {
+				return route, vals
+			}
+		}
+	}
+
+	if len(n.params) > 0 && path != "" && path[0] != '/' {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+
+		for _, child := range n.params {
+			// A param takes the whole segment, or stops before static text
+			// following it in the same segment, such as ":name.json"
+			for e := end; e > 0; e-- {
+				if e < end && strings.IndexByte(child.indices, path[e]) < 0 {
+					continue
+				}
+
+				if child.regexp != nil && !child.regexp.MatchString(path[:e]) {
+					continue
+				}
+
+				if route, vals := child.find(path[e:], append(values, path[:e])); route != nil {
+					return route, vals
+				}
+			}
+		}
+	}
+
+	// Catch-all takes the rest of path, which may be empty or contain "/"
+	if n.catchall != nil && n.catchall.route != nil {
+		return n.catchall.route, append(values, path)
+	}
+
+	return nil, values
+}
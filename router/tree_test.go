@@ -0,0 +1,269 @@
+package router
+
+import (
+	"fmt"
+	"github.com/raythorn/zebra/context"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func serve(r Router, method, path string) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	r.Handle(rw, httptest.NewRequest(method, path, nil))
+	return rw
+}
+
+func echo(name string, keys ...string) Handler {
+	return func(ctx *context.Context) {
+		values := []string{name}
+		for _, key := range keys {
+			values = append(values, key+"="+ctx.Get(key))
+		}
+		ctx.WriteString(strings.Join(values, " "))
+	}
+}
+
+func TestMatch(t *testing.T) {
+
+	r := New()
+	r.Get("/", echo("root"))
+	r.Get("/user", echo("users"))
+	r.Get("/user/new", echo("new"))
+	r.Get("/user/:id", echo("user", "id"))
+	r.Get("/user/:id/friends", echo("friends", "id"))
+	r.Get("/user/:uid/posts/:id", echo("post", "uid", "id"))
+	r.Get("/file/:name.json", echo("json", "name"))
+	r.Get(`/page/(?P<num>\d+)`, echo("page", "num"))
+	r.Get("/page/:slug", echo("slug", "slug"))
+	r.Group("/api",
+		grouped("/status", echo("status")),
+	)
+
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"/", "root"},
+		{"/user", "users"},
+		{"/user/", "users"},
+		{"/user/new", "new"},
+		{"/user/42", "user id=42"},
+		{"/user/42/", "user id=42"},
+		{"/user/42/friends", "friends id=42"},
+		{"/user/42/posts/7", "post uid=42 id=7"},
+		{"/file/a.b.json", "json name=a.b"},
+		{"/page/12", "page num=12"},
+		{"/page/about", "slug slug=about"},
+		{"/api/status", "status"},
+		{"/user/42/unknown", "404 page not found\n"},
+	}
+
+	for _, c := range cases {
+		if body := serve(r, "GET", c.path).Body.String(); body != c.body {
+			t.Errorf("GET %s = %q, want %q", c.path, body, c.body)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+
+	r := New()
+	r.Get("/user/:id<int>", echo("int", "id"))
+	r.Get("/user/:name", echo("name", "name"))
+	r.Get("/order/:id<uuid>", echo("uuid", "id"))
+	r.Get("/tag/:slug<[a-z-]+>", echo("slug", "slug"))
+	r.Get("/api/:ver<enum(v1|v2)>/ping", echo("ping", "ver"))
+	r.Get("/file/:id<int>.json", echo("json", "id"))
+
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"/user/42", "int id=42"},
+		{"/user/-1", "int id=-1"},
+		{"/user/abc", "name name=abc"},
+		{"/order/0b5c4e3a-8c1f-4d5e-9a6b-7c8d9e0f1a2b", "uuid id=0b5c4e3a-8c1f-4d5e-9a6b-7c8d9e0f1a2b"},
+		{"/order/42", "404 page not found\n"},
+		{"/tag/go-lang", "slug slug=go-lang"},
+		{"/tag/Go", "404 page not found\n"},
+		{"/api/v2/ping", "ping ver=v2"},
+		{"/api/v3/ping", "404 page not found\n"},
+		{"/file/7.json", "json id=7"},
+		{"/file/x.json", "404 page not found\n"},
+	}
+
+	for _, c := range cases {
+		if body := serve(r, "GET", c.path).Body.String(); body != c.body {
+			t.Errorf("GET %s = %q, want %q", c.path, body, c.body)
+		}
+	}
+
+	r.Get("/item/:id<int>", echo("item")).Name("item")
+	if url, err := r.URL("item", "id", "abc"); err == nil {
+		t.Errorf("URL(item, id, abc) = %q, want error", url)
+	}
+}
+
+func TestCatchAll(t *testing.T) {
+
+	r := New()
+	r.Get("/files/readme", echo("readme"))
+	r.Get("/files/:name", echo("name", "name"))
+	r.Get("/files/*path", echo("path", "path")).Name("files")
+	r.Get("/files/:dir/index", echo("index", "dir"))
+
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"/files/readme", "readme"},
+		{"/files/a.txt", "name name=a.txt"},
+		{"/files/a/b/c.txt", "path path=a/b/c.txt"},
+		{"/files/a/index", "index dir=a"},
+		{"/files/a/index/x", "path path=a/index/x"},
+		{"/files/", "path path="},
+	}
+
+	for _, c := range cases {
+		if body := serve(r, "GET", c.path).Body.String(); body != c.body {
+			t.Errorf("GET %s = %q, want %q", c.path, body, c.body)
+		}
+	}
+
+	if url, err := r.URL("files", "path", "a b/c.txt"); err != nil || url != "/files/a%20b/c.txt" {
+		t.Errorf("URL(files) = %q, %v, want %q", url, err, "/files/a%20b/c.txt")
+	}
+}
+
+func grouped(pattern string, handler Handler) *Route {
+	g := &Group{}
+	return g.Get(pattern, handler)
+}
+
+func benchRoutes(n int) []string {
+	patterns := make([]string, 0, n)
+	for i := 0; len(patterns) < n; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("/api/v1/resource%d", i),
+			fmt.Sprintf("/api/v1/resource%d/:id", i),
+			fmt.Sprintf("/api/v1/resource%d/:id/items/:item", i),
+			fmt.Sprintf("/static/path%d/index", i),
+		)
+	}
+
+	return patterns[:n]
+}
+
+func benchRouter(b *testing.B, path string) {
+	r := New()
+	for _, pattern := range benchRoutes(1000) {
+		r.Get(pattern, func(ctx *context.Context) {})
+	}
+
+	req := httptest.NewRequest("GET", path, nil)
+	rw := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Handle(rw, req)
+	}
+}
+
+func BenchmarkRouterStatic(b *testing.B) {
+	benchRouter(b, "/static/path240/index")
+}
+
+func BenchmarkRouterParam(b *testing.B) {
+	benchRouter(b, "/api/v1/resource240/42/items/7")
+}
+
+func benchTree(b *testing.B, path string) {
+	g := newGroup()
+	for _, pattern := range benchRoutes(1000) {
+		g.insert("GET", pattern, func(ctx *context.Context) {})
+	}
+	tree := g.lookup()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if route, _ := tree.find(path, nil); route == nil {
+			b.Fatal("route not found")
+		}
+	}
+}
+
+func BenchmarkTreeStatic(b *testing.B) {
+	benchTree(b, "/static/path240/index")
+}
+
+func BenchmarkTreeParam(b *testing.B) {
+	benchTree(b, "/api/v1/resource240/42/items/7")
+}
+
+// benchLinear measures the replaced matching, which looks up exact pattern in a
+// map and then runs every regexp route
+func benchLinear(b *testing.B, path string) {
+	param := regexp.MustCompile(`:[^/#?()\.\\]+`)
+	static := make(map[string]bool)
+	var regexps []*regexp.Regexp
+
+	for _, pattern := range benchRoutes(1000) {
+		if !strings.Contains(pattern, ":") {
+			static[pattern] = true
+			continue
+		}
+
+		exp := param.ReplaceAllStringFunc(pattern, func(m string) string {
+			return fmt.Sprintf(`(?P<%s>[^/#?]+)`, m[1:])
+		})
+		regexps = append(regexps, regexp.MustCompile(exp+`\/?`))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if static[path] {
+			continue
+		}
+
+		found := false
+		for _, exp := range regexps {
+			if matches := exp.FindStringSubmatch(path); len(matches) > 0 && matches[0] == path {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			b.Fatal("route not found")
+		}
+	}
+}
+
+func BenchmarkLinearStatic(b *testing.B) {
+	benchLinear(b, "/static/path240/index")
+}
+
+func BenchmarkLinearParam(b *testing.B) {
+	benchLinear(b, "/api/v1/resource240/42/items/7")
+}
+
+func TestStaticAllocs(t *testing.T) {
+	g := newGroup()
+	for _, pattern := range benchRoutes(1000) {
+		g.insert("GET", pattern, func(ctx *context.Context) {})
+	}
+	tree := g.lookup()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.find("/static/path240/index", nil)
+	})
+
+	if allocs != 0 {
+		t.Errorf("static lookup allocates %v times, want 0", allocs)
+	}
+}
@@ -0,0 +1,190 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// VersionKey is the key of version serving request in context
+const VersionKey = "com.raythorn.zebra.version"
+
+var vendorRegex = regexp.MustCompile(`application/vnd\.([^.+;,\s]+)\.([^+;,\s]+)`)
+
+// Versioning configures how version of API is selected for request, it's selected by
+// path prefix first, such as "/api/v2/users", then header, then media type in Accept
+// header, such as "application/vnd.x.v2+json", and default version at last.
+type Versioning struct {
+	// Prefix is the path before version, such as "/api", version in path is disabled
+	// if it's "-"
+	Prefix string
+
+	// Header is the name of header with version, default "X-API-Version"
+	Header string
+
+	// Vendor is the vendor in media type, such as "x" in "application/vnd.x.v2+json",
+	// any vendor matches if empty
+	Vendor string
+
+	// Default is the version used if request doesn't specify one, the latest version
+	// is used if empty
+	Default string
+}
+
+// version is a route table of API version
+type version struct {
+	name   string
+	table  *Group
+	sunset time.Time
+
+	deprecated bool
+
+	// versioning is the configuration of router, and urlfor builds URL of named route
+	// for requests served by this version
+	versioning *Versioning
+	urlfor     context.URLBuilder
+}
+
+func (r *router) Versioning(config Versioning) {
+	if config.Header == "" {
+		config.Header = "X-API-Version"
+	}
+
+	r.versioning = config
+}
+
+func (r *router) Version(name string, args ...interface{}) *Group {
+
+	var v *version
+	for _, exist := range r.versions {
+		if exist.name == name {
+			v = exist
+		}
+	}
+
+	if v == nil {
+		v = &version{name: name, table: newGroup(), versioning: &r.versioning}
+		v.urlfor = func(name string, pairs ...string) (string, error) {
+			return r.url(v, name, pairs...)
+		}
+		r.versions = append(r.versions, v)
+	}
+
+	g := newGroup()
+	g.group("/", args...)
+	g.pattern = "/"
+	for _, route := range g.routes {
+		route.each(func(rt *Route) { rt.version = v })
+	}
+	v.table.mount(g)
+
+	return g
+}
+
+func (r *router) Deprecate(name string, sunset time.Time) {
+	for _, v := range r.versions {
+		if v.name == name {
+			v.deprecated = true
+			v.sunset = sunset
+		}
+	}
+}
+
+// version selects version for request, and returns it with route tables from the version
+// to the oldest one and path in version, nil returns if no version selected
+func (r *router) version(ctx *context.Context) (*version, []*Group, string) {
+
+	if len(r.versions) == 0 {
+		return nil, nil, ""
+	}
+
+	path := ctx.URL()
+	index := -1
+
+	if prefix := r.versioning.Prefix; prefix != "-" {
+		for i, v := range r.versions {
+			base := prefix + "/" + v.name
+			if path == base || strings.HasPrefix(path, base+"/") {
+				index = i
+				if path = path[len(base):]; path == "" {
+					path = "/"
+				}
+				break
+			}
+		}
+	}
+
+	if index < 0 {
+		if name := ctx.Request().Header.Get(r.versioning.Header); name != "" {
+			index = r.lookupVersion(name)
+		}
+	}
+
+	if index < 0 {
+		for _, match := range vendorRegex.FindAllStringSubmatch(ctx.Request().Header.Get("Accept"), -1) {
+			if r.versioning.Vendor != "" && match[1] != r.versioning.Vendor {
+				continue
+			}
+
+			if index = r.lookupVersion(match[2]); index >= 0 {
+				break
+			}
+		}
+	}
+
+	if index < 0 {
+		if r.versioning.Default != "" {
+			index = r.lookupVersion(r.versioning.Default)
+		} else {
+			index = len(r.versions) - 1
+		}
+	}
+
+	if index < 0 {
+		return nil, nil, ""
+	}
+
+	tables := make([]*Group, 0, index+1)
+	for i := index; i >= 0; i-- {
+		tables = append(tables, r.versions[i].table)
+	}
+
+	return r.versions[index], tables, path
+}
+
+// apply saves version in context, and sets deprecation headers, it's called only when
+// a route of version serves request
+func (v *version) apply(ctx *context.Context) {
+
+	ctx.Set(VersionKey, v.name)
+	ctx.SetURLBuilder(v.urlfor)
+
+	if v.deprecated {
+		ctx.Header("Deprecation", "true")
+		if !v.sunset.IsZero() {
+			ctx.Header("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+		}
+	}
+}
+
+func (r *router) lookupVersion(name string) int {
+	for i, v := range r.versions {
+		if v.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// path returns path served by this version, prefix of version is added unless version
+// is not in path
+func (v *version) path(path string) string {
+	if v.versioning.Prefix == "-" {
+		return path
+	}
+
+	return join(v.versioning.Prefix+"/"+v.name, path)
+}
@@ -0,0 +1,186 @@
+package router
+
+import (
+	"github.com/raythorn/zebra/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersion(t *testing.T) {
+
+	var table, version, path string
+	served := func(name string) Handler {
+		return func(ctx *context.Context) {
+			table, version, path = name, ctx.Get(VersionKey), ctx.Get("id")
+		}
+	}
+
+	r := New()
+	r.Versioning(Versioning{Prefix: "/api", Vendor: "x", Default: "v1"})
+	r.Version("v1",
+		(&Group{}).Get("/users/:id", served("v1")),
+		(&Group{}).Get("/posts/:id", served("v1")),
+	)
+	r.Version("v2", (&Group{}).Get("/users/:id", served("v2")))
+	r.Get("/health", served("none"))
+
+	cases := []struct {
+		target  string
+		header  string
+		value   string
+		table   string
+		version string
+	}{
+		// Version in path
+		{"/api/v2/users/1", "", "", "v2", "v2"},
+		{"/api/v1/users/1", "", "", "v1", "v1"},
+
+		// Route not in v2 falls back to v1, and request is still served as v2
+		{"/api/v2/posts/1", "", "", "v1", "v2"},
+
+		// Version in header or media type, then default version
+		{"/users/1", "X-API-Version", "v2", "v2", "v2"},
+		{"/users/1", "Accept", "application/vnd.x.v2+json", "v2", "v2"},
+		{"/users/1", "Accept", "application/vnd.y.v2+json", "v1", "v1"},
+		{"/users/1", "", "", "v1", "v1"},
+
+		// Routes without version
+		{"/health", "", "", "none", ""},
+		{"/api/v3/users/1", "", "", "", ""},
+	}
+
+	for _, c := range cases {
+		table, version, path = "", "", ""
+
+		req := httptest.NewRequest("GET", c.target, nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		r.Handle(httptest.NewRecorder(), req)
+
+		if table != c.table || version != c.version {
+			t.Errorf("GET %s %s served by %q as %q, want %q as %q", c.target, c.value, table, version, c.table, c.version)
+		}
+
+		if c.table != "" && c.table != "none" && path != "1" {
+			t.Errorf("GET %s id = %q, want 1", c.target, path)
+		}
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+
+	handler := func(ctx *context.Context) {}
+
+	r := New()
+	r.Versioning(Versioning{Prefix: "/api"})
+	r.Version("v1", (&Group{}).Get("/users", handler))
+	r.Version("v2", (&Group{}).Get("/users", handler))
+	r.Get("/health", handler)
+	r.Post("/users", handler)
+	r.Deprecate("v1", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	rw := serve(r, "GET", "/api/v1/users")
+	if rw.Header().Get("Deprecation") != "true" || rw.Header().Get("Sunset") != "Tue, 01 Jan 2030 00:00:00 GMT" {
+		t.Errorf("GET /api/v1/users headers %v, want Deprecation and Sunset", rw.Header())
+	}
+
+	r.Deprecate("v2", time.Time{})
+	cases := []struct {
+		method string
+		target string
+	}{
+		{"GET", "/health"},
+
+		// Version v2 is selected but the route without version serves POST
+		{"POST", "/users"},
+	}
+
+	for _, c := range cases {
+		rw := serve(r, c.method, c.target)
+		if rw.Code != http.StatusOK || rw.Header().Get("Deprecation") != "" {
+			t.Errorf("%s %s = %d, Deprecation %q", c.method, c.target, rw.Code, rw.Header().Get("Deprecation"))
+		}
+	}
+
+	if rw := serve(r, "GET", "/users"); rw.Header().Get("Deprecation") != "true" || rw.Header().Get("Sunset") != "" {
+		t.Errorf("GET /users headers %v, want Deprecation without Sunset", rw.Header())
+	}
+}
+
+func TestVersionHost(t *testing.T) {
+
+	var served string
+	r := New()
+	r.Versioning(Versioning{Prefix: "/api"})
+	r.Version("v1", (&Group{}).Get("/ping", func(ctx *context.Context) { served = "v1" }))
+	r.Host("admin.example.com", (&Group{}).Get("/api/v1/ping", func(ctx *context.Context) { served = "admin" }))
+
+	// Routes of matched host are not shadowed by versions
+	req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+	req.Host = "admin.example.com"
+	r.Handle(httptest.NewRecorder(), req)
+	if served != "admin" {
+		t.Errorf("GET admin.example.com/api/v1/ping served by %q, want admin", served)
+	}
+
+	if serve(r, "GET", "/api/v1/ping"); served != "v1" {
+		t.Errorf("GET example.com/api/v1/ping served by %q, want v1", served)
+	}
+}
+
+func TestVersionURL(t *testing.T) {
+
+	handler := func(ctx *context.Context) {}
+	var built string
+
+	r := New()
+	r.Versioning(Versioning{Prefix: "/api"})
+	r.Version("v1",
+		(&Group{}).Get("/users/:id", func(ctx *context.Context) {
+			built, _ = ctx.URLFor("user", "id", "2")
+		}).Name("user"),
+		(&Group{}).Get("/posts/:id", handler).Name("post"),
+	)
+	v2 := (&Group{}).Get("/users/:id", handler).Name("user")
+	r.Version("v2", v2)
+	r.Get("/health", handler).Name("health")
+
+	cases := []struct {
+		name  string
+		pairs []string
+		url   string
+	}{
+		// The latest version is used without request
+		{"user", []string{"id", "1"}, "/api/v2/users/1"},
+		{"post", []string{"id", "1"}, "/api/v1/posts/1"},
+		{"health", nil, "/health"},
+	}
+
+	for _, c := range cases {
+		if url, err := r.URL(c.name, c.pairs...); err != nil || url != c.url {
+			t.Errorf("URL(%s) = %q, %v, want %q", c.name, url, err, c.url)
+		}
+	}
+
+	if url, err := v2.URL("id", "1"); err != nil || url != "/api/v2/users/1" {
+		t.Errorf("URL of v2 route = %q, %v, want /api/v2/users/1", url, err)
+	}
+
+	// Request served by v1 builds URL of v1
+	if serve(r, "GET", "/api/v1/users/1"); built != "/api/v1/users/2" {
+		t.Errorf("URLFor(user) in v1 = %q, want /api/v1/users/2", built)
+	}
+
+	r.Version("v2", (&Group{}).Get("/profiles/:id", handler).Name("user"))
+	if _, err := r.URL("user", "id", "1"); err == nil {
+		t.Error("URL of name used twice in v2 = nil error, want not unique")
+	}
+
+	r.Versioning(Versioning{Prefix: "-"})
+	if url, err := r.URL("post", "id", "1"); err != nil || url != "/posts/1" {
+		t.Errorf("URL(post) without version in path = %q, %v, want /posts/1", url, err)
+	}
+}
@@ -0,0 +1,36 @@
+package zebra
+
+import (
+	"fmt"
+	"github.com/raythorn/zebra/context"
+	"github.com/raythorn/zebra/router"
+	"io"
+	"text/tabwriter"
+)
+
+//PrintRoutes writes route table to w, one route for each method, it can be used by a
+//command of application to show routes without running server
+func (a *App) PrintRoutes(w io.Writer) {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHOST\tVERSION\tGROUP\tMIDDLEWARES")
+	for _, route := range a.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", route.Method, route.Pattern, route.Name,
+			route.Host, route.Version, route.Group, route.Middlewares)
+	}
+	tw.Flush()
+}
+
+//DebugRoutes adds an endpoint with path showing route table, as json if client accepts
+//json, otherwise as text. Don't expose it in production.
+func (a *App) DebugRoutes(path string) *router.Route {
+	return a.Get(path, func(ctx *context.Context) {
+		if ctx.AcceptsJSON() {
+			ctx.JSON(a.Routes(), true)
+			return
+		}
+
+		ctx.Header("Content-Type", "text/plain; charset=utf-8")
+		a.PrintRoutes(ctx)
+	})
+}
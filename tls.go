@@ -0,0 +1,261 @@
+package zebra
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/raythorn/zebra/log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+//certificate is a cert/key pair loaded from files, it will be reloaded if files changed
+type certificate struct {
+	certFile string
+	keyFile  string
+	modtime  time.Time
+	cert     *tls.Certificate
+}
+
+//certStore holds all certificates of TLS listener, and selects certificate by SNI
+type certStore struct {
+	sync.RWMutex
+	certs []*certificate
+	quit  chan bool
+}
+
+func newCertStore(certFiles, keyFiles []string) (*certStore, error) {
+
+	if len(certFiles) == 0 || len(certFiles) != len(keyFiles) {
+		return nil, errors.New("TLS: cert files and key files mismatch")
+	}
+
+	store := &certStore{quit: make(chan bool)}
+	for i := range certFiles {
+		c := &certificate{certFile: certFiles[i], keyFile: keyFiles[i]}
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+		store.certs = append(store.certs, c)
+	}
+
+	return store, nil
+}
+
+func (c *certificate) load() error {
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("TLS: load %s fail: %s", c.certFile, err)
+	}
+
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("TLS: parse %s fail: %s", c.certFile, err)
+		}
+	}
+
+	c.cert = &cert
+	c.modtime = c.stamp()
+	return nil
+}
+
+//stamp returns the latest modification time of cert and key file
+func (c *certificate) stamp() time.Time {
+	var stamp time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		if fi, err := os.Stat(file); err == nil && fi.ModTime().After(stamp) {
+			stamp = fi.ModTime()
+		}
+	}
+
+	return stamp
+}
+
+//GetCertificate selects certificate matches the server name of client hello, the first
+//certificate will be used if no one matches
+func (s *certStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, c := range s.certs {
+		if hello.SupportsCertificate(c.cert) == nil {
+			return c.cert, nil
+		}
+	}
+
+	return s.certs[0].cert, nil
+}
+
+//watch reloads certificates modified on disk every interval, and keeps the old one if reload fail
+func (s *certStore) watch(interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.reload()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *certStore) reload() {
+	s.RLock()
+	certs := s.certs
+	s.RUnlock()
+
+	for _, c := range certs {
+		if !c.stamp().After(c.modtime) {
+			continue
+		}
+
+		fresh := &certificate{certFile: c.certFile, keyFile: c.keyFile}
+		if err := fresh.load(); err != nil {
+			log.Error("%s", err)
+			continue
+		}
+
+		s.Lock()
+		c.cert, c.modtime = fresh.cert, fresh.modtime
+		s.Unlock()
+
+		log.Info("TLS: reload certificate %s", c.certFile)
+	}
+}
+
+func (s *certStore) close() {
+	close(s.quit)
+}
+
+//tlsConfig builds tls config with certificates, min version and cipher suites in Env
+func tlsConfig(env *Environment, store *certStore) (*tls.Config, error) {
+
+	version, ok := tlsVersions[env.TLSMinVersion()]
+	if !ok {
+		return nil, errors.New("TLS: unknown version " + env.TLSMinVersion())
+	}
+
+	config := &tls.Config{
+		MinVersion:     version,
+		GetCertificate: store.GetCertificate,
+	}
+
+	if names := env.TLSCiphers(); len(names) > 0 {
+		suites := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+
+		for _, name := range names {
+			id, ok := suites[name]
+			if !ok {
+				return nil, errors.New("TLS: unknown cipher suite " + name)
+			}
+			config.CipherSuites = append(config.CipherSuites, id)
+		}
+	}
+
+	return config, nil
+}
+
+//redirect returns a handler which redirects all requests to TLS port permanently
+func redirect(env *Environment) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if port := env.TLSPort(); port != 443 {
+			host = net.JoinHostPort(host, fmt.Sprintf("%d", port))
+		}
+
+		http.Redirect(rw, req, "https://"+host+req.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+//Add a cert/key pair for HTTPS, which will be selected by server name(SNI) of client, the
+//pair set by EnableTLS is used if no one matches
+func (e *Environment) AddTLSCert(cert, key string) {
+	e.Lock()
+	defer e.Unlock()
+
+	if certs := e.data["Zebra:TLSCERTS"]; certs != "" {
+		e.data["Zebra:TLSCERTS"] = certs + "," + cert
+		e.data["Zebra:TLSKEYS"] = e.data["Zebra:TLSKEYS"] + "," + key
+	} else {
+		e.data["Zebra:TLSCERTS"] = cert
+		e.data["Zebra:TLSKEYS"] = key
+	}
+}
+
+//Get all https cert files, the one set by EnableTLS comes first
+func (e *Environment) TLSCerts() []string {
+	return append([]string{e.TLSCert()}, e.GetStrings("Zebra:TLSCERTS", nil)...)
+}
+
+//Get all https key files, the one set by EnableTLS comes first
+func (e *Environment) TLSKeys() []string {
+	return append([]string{e.TLSKey()}, e.GetStrings("Zebra:TLSKEYS", nil)...)
+}
+
+//Set minimal TLS version, "1.0", "1.1", "1.2" or "1.3"
+func (e *Environment) SetTLSMinVersion(version string) {
+	e.Set("Zebra:TLSMINVERSION", version)
+}
+
+//Get minimal TLS version, default "1.2"
+func (e *Environment) TLSMinVersion() string {
+	if version := e.Get("Zebra:TLSMINVERSION"); version != "" {
+		return version
+	}
+
+	return "1.2"
+}
+
+//Set cipher suites with names, such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS 1.3 cipher
+//suites are not configurable
+func (e *Environment) SetTLSCiphers(names ...string) {
+	e.Set("Zebra:TLSCIPHERS", strings.Join(names, ","))
+}
+
+//Get cipher suite names, empty means go default
+func (e *Environment) TLSCiphers() []string {
+	return e.GetStrings("Zebra:TLSCIPHERS", nil)
+}
+
+//Set interval of checking certificate files changes, 0 disables reloading
+func (e *Environment) SetTLSReload(interval time.Duration) {
+	e.Set("Zebra:TLSRELOAD", interval.String())
+}
+
+//Get interval of checking certificate files changes, default 1 minute
+func (e *Environment) TLSReload() time.Duration {
+	return e.GetDuration("Zebra:TLSRELOAD", time.Minute)
+}
+
+//Enable or disable redirecting all http requests to https
+func (e *Environment) SetTLSRedirect(enable bool) {
+	e.Set("Zebra:TLSREDIRECT", fmt.Sprintf("%t", enable))
+}
+
+//Check if http requests redirect to https, works only if TLS enabled
+func (e *Environment) TLSRedirect() bool {
+	return e.TLS() && e.GetBool("Zebra:TLSREDIRECT", false)
+}
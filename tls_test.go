@@ -0,0 +1,171 @@
+package zebra
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//writeCert writes a self-signed certificate for host with serial, and returns cert and key file
+func writeCert(t *testing.T, dir, host string, serial int64) (string, string) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, host+".crt"), filepath.Join(dir, host+".key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	return certFile, keyFile
+}
+
+//handshake connects to addr with server name, and returns certificate of server
+func handshake(t *testing.T, addr, name string) *x509.Certificate {
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: name, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0]
+}
+
+func TestCertStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "zebra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certA, keyA := writeCert(t, dir, "a.example.com", 1)
+	certB, keyB := writeCert(t, dir, "b.example.com", 2)
+
+	store, err := newCertStore([]string{certA, certB}, []string{keyA, keyB})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := newEnvironment()
+	config, err := tlsConfig(env, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}(conn)
+		}
+	}()
+
+	addr := ln.Addr().String()
+
+	// Certificate is selected by SNI, and the first one is used if no one matches
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"a.example.com", "a.example.com"},
+		{"b.example.com", "b.example.com"},
+		{"c.example.com", "a.example.com"},
+	}
+
+	for _, c := range cases {
+		if cert := handshake(t, addr, c.name); cert.Subject.CommonName != c.want {
+			t.Errorf("certificate for %s = %s, want %s", c.name, cert.Subject.CommonName, c.want)
+		}
+	}
+
+	// Modified certificate is reloaded, and broken one keeps the old certificate
+	writeCert(t, dir, "b.example.com", 3)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certB, later, later)
+	store.reload()
+
+	if cert := handshake(t, addr, "b.example.com"); cert.SerialNumber.Int64() != 3 {
+		t.Errorf("serial of reloaded certificate = %d, want 3", cert.SerialNumber)
+	}
+
+	ioutil.WriteFile(certA, []byte("broken"), 0600)
+	later = later.Add(time.Minute)
+	os.Chtimes(certA, later, later)
+	store.reload()
+
+	if cert := handshake(t, addr, "a.example.com"); cert.SerialNumber.Int64() != 1 {
+		t.Errorf("serial after broken reload = %d, want 1", cert.SerialNumber)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+
+	cases := []struct {
+		port     int
+		url      string
+		location string
+	}{
+		{443, "http://example.com/users?page=2", "https://example.com/users?page=2"},
+		{443, "http://example.com:8080/", "https://example.com/"},
+		{8443, "http://example.com:8080/users", "https://example.com:8443/users"},
+		{8443, "http://[::1]:8080/users", "https://[::1]:8443/users"},
+	}
+
+	for _, c := range cases {
+		env := newEnvironment()
+		env.EnableTLS("cert.pem", "key.pem", "", c.port)
+
+		rw := httptest.NewRecorder()
+		redirect(env).ServeHTTP(rw, httptest.NewRequest("GET", c.url, nil))
+
+		if rw.Code != 301 || rw.Header().Get("Location") != c.location {
+			t.Errorf("redirect %s with TLS port %d = %d %s, want 301 %s", c.url, c.port, rw.Code, rw.Header().Get("Location"), c.location)
+		}
+	}
+}
@@ -6,23 +6,101 @@
 package zebra
 
 import (
+	"context"
+	"github.com/raythorn/zebra/openapi"
 	"github.com/raythorn/zebra/oss"
 	"github.com/raythorn/zebra/router"
+	"io"
+	"net/http"
+	"time"
 )
 
 var (
-	zebra *app
+	zebra *App
 	Env   *Environment
+	g     *router.Group
 )
 
 func init() {
-	zebra = &app{router.New(), &router.Group{}}
-	Env = &Environment{data: make(map[string]string)}
+	zebra = New()
+	zebra.shared = true
+	Env = zebra.Env
+	g = &router.Group{}
 }
 
-//Run starts a http(s) server
+//Default returns the default App which package-level functions work on
+func Default() *App {
+	return zebra
+}
+
+//Run starts a http(s) server, and blocks until the server is shut down, either by Shutdown
+//or by receiving SIGINT/SIGTERM, in which case in-flight requests are drained within
+//Env.ShutdownTimeout()
 func Run() {
-	zebra.run()
+	zebra.Run()
+}
+
+//Shutdown gracefully stops the server, it stops accepting connections, waits for in-flight
+//requests until ctx is done, and then closes log channels, registered cache and db engines
+func Shutdown(ctx context.Context) error {
+	return zebra.Shutdown(ctx)
+}
+
+//OnStart adds a hook to default App which will be called before listening
+func OnStart(hook func() error) {
+	zebra.OnStart(hook)
+}
+
+//OnReady adds a hook to default App which will be called after all listeners are serving
+func OnReady(hook func() error) {
+	zebra.OnReady(hook)
+}
+
+//OnShutdown adds a hook to default App which will be called after in-flight requests drained
+func OnShutdown(hook func() error) {
+	zebra.OnShutdown(hook)
+}
+
+//Health adds liveness and readiness endpoints to default App, such as "/healthz" and "/readyz"
+func Health(liveness, readiness string) {
+	zebra.Health(liveness, readiness)
+}
+
+//Routes returns route table of default App, one route for each method
+func Routes() []router.RouteInfo {
+	return zebra.Routes()
+}
+
+//PrintRoutes writes route table of default App to w
+func PrintRoutes(w io.Writer) {
+	zebra.PrintRoutes(w)
+}
+
+//DebugRoutes adds an endpoint showing route table of default App
+func DebugRoutes(path string) *router.Route {
+	return zebra.DebugRoutes(path)
+}
+
+//OpenAPI serves OpenAPI document of default App at path+"/openapi.json" and path+"/openapi.yaml",
+//and a viewer of document at path
+func OpenAPI(path string, info openapi.Info) {
+	zebra.OpenAPI(path, info)
+}
+
+//Check adds a readiness check to default App
+func Check(name string, check func() error) {
+	zebra.Check(name, check)
+}
+
+//Upgrade starts a new process of current binary, and hands listening sockets over to it,
+//then call Shutdown to drain current process, it's triggered by SIGUSR2 when running
+func Upgrade() error {
+	return zebra.Upgrade()
+}
+
+//Connections returns counts of active and idle connections of default App
+func Connections() (active, idle int) {
+	return zebra.Connections()
 }
 
 //Insert midware to http server, which will be called before each request handled.
@@ -30,48 +108,59 @@ func Use(handler router.Midware) {
 	zebra.Use(handler)
 }
 
-func Oss(pattern, root string, archive oss.Archive) {
-	zebra.Oss(pattern, root, archive)
+//Wrap adds middlewares wrapping handlers of all routes, middleware calls next to run the
+//rest of chain and handler
+func Wrap(middlewares ...router.Middleware) {
+	zebra.Wrap(middlewares...)
+}
+
+//Mount serves all requests under prefix with a net/http handler, prefix is stripped from path
+func Mount(prefix string, handler http.Handler) {
+	zebra.Mount(prefix, handler)
+}
+
+func Oss(pattern, root string, archive oss.Archive) *router.Route {
+	return zebra.Oss(pattern, root, archive)
 }
 
 //Get add a GET handler, which used to get data from server
-func Get(pattern string, handler router.Handler) {
-	zebra.Get(pattern, handler)
+func Get(pattern string, handler router.Handler) *router.Route {
+	return zebra.Get(pattern, handler)
 }
 
 //Patch add a PATCH handler, which used to patch existed data
-func Patch(pattern string, handler router.Handler) {
-	zebra.Patch(pattern, handler)
+func Patch(pattern string, handler router.Handler) *router.Route {
+	return zebra.Patch(pattern, handler)
 }
 
 //Put add a PUT handler, which used to update data
-func Put(pattern string, handler router.Handler) {
-	zebra.Put(pattern, handler)
+func Put(pattern string, handler router.Handler) *router.Route {
+	return zebra.Put(pattern, handler)
 }
 
 //Post add a POST handler, which used to create resource
-func Post(pattern string, handler router.Handler) {
-	zebra.Post(pattern, handler)
+func Post(pattern string, handler router.Handler) *router.Route {
+	return zebra.Post(pattern, handler)
 }
 
 //Delete add a DELETE handler, which used to delete resource from server
-func Delete(pattern string, handler router.Handler) {
-	zebra.Delete(pattern, handler)
+func Delete(pattern string, handler router.Handler) *router.Route {
+	return zebra.Delete(pattern, handler)
 }
 
 //Head add a HEAD handler
-func Head(pattern string, handler router.Handler) {
-	zebra.Head(pattern, handler)
+func Head(pattern string, handler router.Handler) *router.Route {
+	return zebra.Head(pattern, handler)
 }
 
 //Options add a OPTIONS handler
-func Options(pattern string, handler router.Handler) {
-	zebra.Options(pattern, handler)
+func Options(pattern string, handler router.Handler) *router.Route {
+	return zebra.Options(pattern, handler)
 }
 
 //Any add a ANY handler, which can response to all method
-func Any(pattern string, handler router.Handler) {
-	zebra.Any(pattern, handler)
+func Any(pattern string, handler router.Handler) *router.Route {
+	return zebra.Any(pattern, handler)
 }
 
 //NotFound add a not found handler, which used to be the handler when request not found
@@ -84,54 +173,107 @@ func NotAllowed(handler router.Handler) {
 	zebra.NotAllowed(handler)
 }
 
+//Recovery add a recovery handler, which used to write response when handler panics, the
+//stack is logged before it called
+func Recovery(handler router.RecoveryHandler) {
+	zebra.Recovery(handler)
+}
+
+//MaxBody sets max size of request body in bytes, larger body is rejected with 413, route
+//can change it with MaxBody of route
+func MaxBody(size int64) {
+	zebra.MaxBody(size)
+}
+
+//URL builds path of the route with name, such as URL("user.show", "id", "42")
+func URL(name string, pairs ...string) (string, error) {
+	return zebra.URL(name, pairs...)
+}
+
 //Group assemble handlers with same prefix together, routes can be routes and sub-groups, with
 //group you can add midwares with Before and After, Before add midware to be called before
-//handler called and After add midware to be called after handler called
+//handler called and After add midware to be called after handler called, and Use adds
+//middlewares wrapping handlers
 func Group(prefix string, routes ...interface{}) *router.Group {
 	return zebra.Router.Group(prefix, routes...)
 }
 
+//Host assemble routes and groups served only for requests to host, host can have params,
+//such as ":tenant.example.com", and requests to other hosts are served by routes without host
+func Host(pattern string, routes ...interface{}) *router.Group {
+	return zebra.Router.Host(pattern, routes...)
+}
+
+//Version assemble routes and groups of API version, a route not in the version falls back to
+//older versions, which are added before it
+func Version(name string, routes ...interface{}) *router.Group {
+	return zebra.Router.Version(name, routes...)
+}
+
+//Versioning configures how version of API is selected, by path prefix, header, media type in
+//Accept header or default version
+func Versioning(config router.Versioning) {
+	zebra.Versioning(config)
+}
+
+//Deprecate marks version deprecated with sunset time, zero sunset means not scheduled
+func Deprecate(name string, sunset time.Time) {
+	zebra.Deprecate(name, sunset)
+}
+
+//Resource adds RESTful routes of controller, which implements any of router.Lister, Creator,
+//Shower, Updater, Patcher and Destroyer, as "GET /users", "POST /users", "GET /users/:id",
+//"PUT /users/:id", "PATCH /users/:id" and "DELETE /users/:id"
+func Resource(pattern string, controller interface{}) *router.Group {
+	return zebra.Router.Resource(pattern, controller)
+}
+
+//GResource add a resource to group, see Resource
+func GResource(pattern string, controller interface{}) *router.Group {
+	return g.Resource(pattern, controller)
+}
+
 //GSub add a sub-group
 func GSub(prefix string, routes ...interface{}) *router.Group {
-	return zebra.g.Sub(prefix, routes...)
+	return g.Sub(prefix, routes...)
 }
 
 //GGet add a grouped GET handler
 func GGet(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Get(pattern, handler)
+	return g.Get(pattern, handler)
 }
 
 //GPatch add a grouped PATCH handler
 func GPatch(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Patch(pattern, handler)
+	return g.Patch(pattern, handler)
 }
 
 //GPut add a grouped PUT handler
 func GPut(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Put(pattern, handler)
+	return g.Put(pattern, handler)
 }
 
 //GPost add a grouped POST handler
 func GPost(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Post(pattern, handler)
+	return g.Post(pattern, handler)
 }
 
 //GDelete add a grouped DELETE handler
 func GDelete(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Delete(pattern, handler)
+	return g.Delete(pattern, handler)
 }
 
 //GHead add a grouped HEAD handler
 func GHead(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Head(pattern, handler)
+	return g.Head(pattern, handler)
 }
 
 //GOptions add a grouped OPTIONS handler
 func GOptions(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Options(pattern, handler)
+	return g.Options(pattern, handler)
 }
 
 //GAny add a grouped ANY handler
 func GAny(pattern string, handler router.Handler) *router.Route {
-	return zebra.g.Any(pattern, handler)
+	return g.Any(pattern, handler)
 }
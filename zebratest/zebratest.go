@@ -0,0 +1,322 @@
+// Copyright 2016 Derek Ray. All rights reserved.
+// Use of this source code is governed by Apache License 2.0
+// that can be found in the LICENSE file.
+
+// Package zebratest drives a zebra App in process for testing handlers.
+//
+// Requests are served by App.ServeHTTP with httptest, no port is listened, and the memory
+// cache engine Ant is registered for the App, so handlers using cache work without redis.
+//
+//	s := zebratest.New(t, app)
+//	s.Post("/user").JSON(user).Token(token).Do().
+//		Status(201).
+//		JSONPath("data.name", "zebra")
+package zebratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/raythorn/zebra"
+	"github.com/raythorn/zebra/auth"
+	"github.com/raythorn/zebra/cache"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//Server serves requests with an App in process
+type Server struct {
+	t   testing.TB
+	app *zebra.App
+}
+
+//New creates a Server for app, and replaces cache engine of app with memory cache engine Ant,
+//the engine registered with cache.Register is replaced too if app is the default App. Engines
+//replaced are restored when test finishes.
+func New(t testing.TB, app *zebra.App) *Server {
+
+	engine := (&cache.Ant{}).Make("zebratest")
+	if engine == nil {
+		t.Fatalf("zebratest: make cache engine failed")
+	}
+
+	previous := app.SwapCache(engine)
+	shared := app == zebra.Default()
+
+	var global cache.Cache
+	if shared {
+		global = cache.Swap(engine)
+	}
+
+	t.Cleanup(func() {
+		app.SwapCache(previous)
+		if shared {
+			cache.Swap(global)
+		}
+		engine.Factory().Destroy()
+	})
+
+	return &Server{t: t, app: app}
+}
+
+//Request creates a request with method and path, path can contain query string
+func (s *Server) Request(method, path string) *Request {
+	return &Request{
+		server: s,
+		method: method,
+		path:   path,
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+}
+
+//Get creates a GET request
+func (s *Server) Get(path string) *Request {
+	return s.Request("GET", path)
+}
+
+//Post creates a POST request
+func (s *Server) Post(path string) *Request {
+	return s.Request("POST", path)
+}
+
+//Put creates a PUT request
+func (s *Server) Put(path string) *Request {
+	return s.Request("PUT", path)
+}
+
+//Patch creates a PATCH request
+func (s *Server) Patch(path string) *Request {
+	return s.Request("PATCH", path)
+}
+
+//Delete creates a DELETE request
+func (s *Server) Delete(path string) *Request {
+	return s.Request("DELETE", path)
+}
+
+//Head creates a HEAD request
+func (s *Server) Head(path string) *Request {
+	return s.Request("HEAD", path)
+}
+
+//Options creates a OPTIONS request
+func (s *Server) Options(path string) *Request {
+	return s.Request("OPTIONS", path)
+}
+
+//Request is a fluent builder of http request
+type Request struct {
+	server *Server
+	method string
+	path   string
+	header http.Header
+	query  url.Values
+	body   io.Reader
+}
+
+//Header sets request header
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+//Query adds a query parameter
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+//Body sets raw request body
+func (r *Request) Body(body []byte) *Request {
+	r.body = bytes.NewReader(body)
+	return r
+}
+
+//Form sets url-encoded form as request body
+func (r *Request) Form(form url.Values) *Request {
+	r.header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.body = strings.NewReader(form.Encode())
+	return r
+}
+
+//JSON marshals data as request body
+func (r *Request) JSON(data interface{}) *Request {
+	content, err := json.Marshal(data)
+	if err != nil {
+		r.server.t.Fatalf("zebratest: marshal json fail: %s", err)
+	}
+
+	r.header.Set("Content-Type", "application/json; charset=utf-8")
+	r.body = bytes.NewReader(content)
+	return r
+}
+
+//Token signs token and sets it as "Authorization: Bearer <token>"
+func (r *Request) Token(token *auth.Token) *Request {
+	signed, err := token.Sign()
+	if err != nil {
+		r.server.t.Fatalf("zebratest: sign token fail: %s", err)
+	}
+
+	r.header.Set("Authorization", "Bearer "+signed)
+	return r
+}
+
+//APISign signs request with token and query parameters, and adds query parameters "token",
+//"timestamp" and "sign", so call it after all Query set
+func (r *Request) APISign(token string) *Request {
+
+	path := r.path
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	sign := auth.NewAPISign()
+	sign.Set("url", path)
+	sign.Set("token", token)
+	sign.Set("timestamp", timestamp)
+	for key := range r.query {
+		sign.Set(key, r.query.Get(key))
+	}
+
+	signature, err := sign.Sign()
+	if err != nil {
+		r.server.t.Fatalf("zebratest: %s", err)
+	}
+
+	r.query.Set("token", token)
+	r.query.Set("timestamp", timestamp)
+	r.query.Set("sign", signature)
+	return r
+}
+
+//Do serves the request, and returns response for assertions
+func (r *Request) Do() *Response {
+
+	target := r.path
+	if len(r.query) > 0 {
+		if strings.Contains(target, "?") {
+			target += "&" + r.query.Encode()
+		} else {
+			target += "?" + r.query.Encode()
+		}
+	}
+
+	req := httptest.NewRequest(r.method, target, r.body)
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+
+	recorder := httptest.NewRecorder()
+	r.server.app.ServeHTTP(recorder, req)
+
+	return &Response{ResponseRecorder: recorder, t: r.server.t, request: r.method + " " + target}
+}
+
+//Response is a served response with assertions, each failed assertion reports an error but
+//doesn't stop the test
+type Response struct {
+	*httptest.ResponseRecorder
+	t       testing.TB
+	request string
+}
+
+//Status asserts response status code
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+
+	if r.Code != code {
+		r.t.Errorf("%s: status %d, want %d", r.request, r.Code, code)
+	}
+
+	return r
+}
+
+//Header asserts response header
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+
+	if got := r.Result().Header.Get(key); got != value {
+		r.t.Errorf("%s: header %s is %q, want %q", r.request, key, got, value)
+	}
+
+	return r
+}
+
+//BodyString asserts response body
+func (r *Response) BodyString(body string) *Response {
+	r.t.Helper()
+
+	if got := r.Body.String(); got != body {
+		r.t.Errorf("%s: body %q, want %q", r.request, got, body)
+	}
+
+	return r
+}
+
+//JSONPath asserts value in json response with path, keys and array indexes are joined with
+//".", such as "data.users.0.name", values are compared in their printed form, so 42 equals
+//json number 42
+func (r *Response) JSONPath(path string, want interface{}) *Response {
+	r.t.Helper()
+
+	got, err := r.Lookup(path)
+	if err != nil {
+		r.t.Errorf("%s: %s", r.request, err)
+		return r
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		r.t.Errorf("%s: json %s is %v, want %v", r.request, path, got, want)
+	}
+
+	return r
+}
+
+//Decode unmarshals json response into v
+func (r *Response) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body.Bytes(), v)
+}
+
+//Lookup returns value in json response with path, see JSONPath
+func (r *Response) Lookup(path string) (interface{}, error) {
+
+	var data interface{}
+	if err := r.Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode json fail: %s", err)
+	}
+
+	if path == "" {
+		return data, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := data.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("json %s not found", path)
+			}
+			data = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("json %s not found", path)
+			}
+			data = node[i]
+		default:
+			return nil, fmt.Errorf("json %s not found", path)
+		}
+	}
+
+	return data, nil
+}
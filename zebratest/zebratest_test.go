@@ -0,0 +1,70 @@
+package zebratest
+
+import (
+	"github.com/raythorn/zebra"
+	"github.com/raythorn/zebra/cache"
+	"github.com/raythorn/zebra/context"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+
+	app := zebra.New()
+	app.Get("/user/:id", func(ctx *context.Context) {
+		ctx.Header("X-User", ctx.Get("id"))
+		ctx.JSON(map[string]interface{}{"data": map[string]interface{}{"id": ctx.Get("id"), "tags": []int{1, 2}}}, false)
+	})
+	app.Post("/echo", func(ctx *context.Context) {
+		app.Cache().Set("echo", string(ctx.Body()))
+		ctx.Write(ctx.Body())
+	})
+
+	s := New(t, app)
+
+	s.Get("/user/42").Do().
+		Status(200).
+		Header("X-User", "42").
+		JSONPath("data.id", "42").
+		JSONPath("data.tags.1", 2)
+
+	s.Post("/echo").JSON(map[string]string{"name": "zebra"}).Do().
+		Status(200).
+		BodyString(`{"name":"zebra"}`)
+
+	if echo := app.Cache().Get("echo"); echo != `{"name":"zebra"}` {
+		t.Errorf("cache echo = %v", echo)
+	}
+}
+
+func TestRestore(t *testing.T) {
+
+	engine := (&cache.Ant{}).Make("")
+	defer engine.Factory().Destroy()
+
+	app := zebra.New()
+	app.SwapCache(engine)
+	global := cache.Swap(engine)
+	defer cache.Swap(global)
+
+	t.Run("app", func(t *testing.T) {
+		New(t, app)
+		if app.Cache() == engine {
+			t.Error("cache of app is not replaced")
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		New(t, zebra.Default())
+		if cache.Engine() == engine {
+			t.Error("registered cache is not replaced")
+		}
+	})
+
+	if app.Cache() != engine {
+		t.Error("cache of app is not restored")
+	}
+
+	if cache.Engine() != engine {
+		t.Error("registered cache is not restored")
+	}
+}